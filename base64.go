@@ -0,0 +1,65 @@
+package sse
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+//Base64 configures decoding of event data that the server sends base64
+//encoded, e.g. binary payloads.
+type Base64 struct {
+	//Types lists the event types whose data is decoded. If empty, the data of
+	//all events is.
+	Types []string
+
+	//Encoding is the alphabet and padding used. Defaults to
+	//base64.StdEncoding.
+	Encoding *base64.Encoding
+
+	//Drop makes events whose data is not valid base64 be dropped, logging
+	//each at LogError, instead of aborting the connection with a
+	//*Base64Error, which is the default.
+	Drop bool
+}
+
+//Base64Error is returned when the data of an event that should be base64
+//encoded is not.
+type Base64Error struct {
+	URI string
+	ID  string
+	Err error
+}
+
+func (e *Base64Error) Error() string {
+	return fmt.Sprintf("%s sent event %q with invalid base64 data: %v", e.URI, e.ID, e.Err)
+}
+
+func (e *Base64Error) Unwrap() error {
+	return e.Err
+}
+
+//decode replaces the data of ev with its decoded form, if its type is one to
+//decode.
+func (b *Base64) decode(uri string, ev *Event) error {
+	if len(b.Types) != 0 {
+		var match bool
+		for _, typ := range b.Types {
+			match = match || typ == ev.Type
+		}
+		if !match {
+			return nil
+		}
+	}
+
+	enc := b.Encoding
+	if enc == nil {
+		enc = base64.StdEncoding
+	}
+	data := make([]byte, enc.DecodedLen(len(ev.Data)))
+	n, err := enc.Decode(data, ev.Data)
+	if err != nil {
+		return &Base64Error{URI: uri, ID: ev.ID, Err: err}
+	}
+	ev.Data = data[:n]
+	return nil
+}
@@ -0,0 +1,53 @@
+package sse
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBase64(t *testing.T) {
+	var (
+		payload = []byte{0x00, 0xff, 0x10, 0x80, '\n'}
+		encoded = base64.StdEncoding.EncodeToString(payload)
+		stream  = "event: blob\ndata: " + encoded + "\n\n" +
+			"event: text\ndata: plain text\n\n" +
+			"event: blob\ndata: not base64!\n\n" +
+			"event: blob\ndata: " + encoded[:4] + "\ndata: " + encoded[4:] + "\n\n"
+	)
+
+	t.Run("drop", func(t *testing.T) {
+		evCh := make(chan *Event, 4)
+		opts := &Options{Base64: &Base64{Types: []string{"blob"}, Drop: true}}
+		require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+		close(evCh)
+
+		var data [][]byte
+		for ev := range evCh {
+			data = append(data, ev.Data)
+		}
+		assert.Equal(t, [][]byte{payload, []byte("plain text"), payload}, data)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		evCh := make(chan *Event, 4)
+		opts := &Options{Base64: &Base64{Types: []string{"blob"}}}
+		err := newSession("uri", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream))
+		var b64Err *Base64Error
+		require.ErrorAs(t, err, &b64Err)
+		assert.Equal(t, "uri", b64Err.URI)
+		assert.Len(t, evCh, 2)
+	})
+
+	t.Run("all types", func(t *testing.T) {
+		evCh := make(chan *Event, 1)
+		opts := &Options{Base64: &Base64{Encoding: base64.RawURLEncoding}}
+		stream := "data: " + base64.RawURLEncoding.EncodeToString(payload) + "\n\n"
+		require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+		assert.Equal(t, payload, (<-evCh).Data)
+	})
+}
@@ -0,0 +1,52 @@
+package sse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+//Checksum configures verification of event data against a checksum that the
+//server sends in a custom field of each event, e.g. "checksum: <hex>".
+type Checksum struct {
+	//Field is the name of the field holding the hex encoded checksum.
+	Field string
+
+	//New returns the hash used to compute checksums. Defaults to sha256.New.
+	New func() hash.Hash
+
+	//Drop makes events whose data does not match their checksum be dropped,
+	//logging each at LogError, instead of aborting the connection with a
+	//*ChecksumError, which is the default.
+	Drop bool
+}
+
+//ChecksumError is returned when the data of an event does not match the
+//checksum sent along with it.
+type ChecksumError struct {
+	URI  string
+	ID   string
+	Want string
+	Got  string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%s sent event %q with checksum %s, computed %s", e.URI, e.ID, e.Want, e.Got)
+}
+
+//verify checks ev's data against want, the value of its checksum field.
+func (c *Checksum) verify(uri string, ev *Event, want string) error {
+	newHash := c.New
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	h := newHash()
+	h.Write(ev.Data)
+	got := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(got, want) {
+		return &ChecksumError{URI: uri, ID: ev.ID, Want: want, Got: got}
+	}
+	return nil
+}
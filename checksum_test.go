@@ -0,0 +1,50 @@
+package sse
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("payload"))
+	var (
+		good   = "id: 1\ndata: payload\nchecksum: " + hex.EncodeToString(sum[:]) + "\n\n"
+		bad    = "id: 2\ndata: pay1oad\nchecksum: " + hex.EncodeToString(sum[:]) + "\n\n"
+		stream = good + bad + "id: 3\ndata: unverified\n\n"
+	)
+
+	t.Run("error", func(t *testing.T) {
+		var (
+			evCh = make(chan *Event, 3)
+			s    = newSession("uri", Target{Chan: evCh}, &Options{Checksum: &Checksum{Field: "checksum"}})
+		)
+		err := s.loop(context.Background(), strings.NewReader(stream))
+
+		var checksumErr *ChecksumError
+		require.True(t, errors.As(err, &checksumErr))
+		assert.Equal(t, "2", checksumErr.ID)
+		require.Len(t, evCh, 1)
+		assert.Equal(t, []byte("payload"), (<-evCh).Data)
+		assert.Equal(t, "1", s.id)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		evCh := make(chan *Event, 3)
+		s := newSession("uri", Target{Chan: evCh}, &Options{Checksum: &Checksum{Field: "checksum", Drop: true}})
+		require.NoError(t, s.loop(context.Background(), strings.NewReader(stream)))
+		close(evCh)
+
+		var ids []string
+		for event := range evCh {
+			ids = append(ids, event.ID)
+		}
+		assert.Equal(t, []string{"1", "3"}, ids)
+	})
+}
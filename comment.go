@@ -0,0 +1,50 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+)
+
+//CommentMode is how comment lines are treated, see Options.CommentMode.
+type CommentMode int
+
+const (
+	//CommentIgnore ignores comments. It is the default.
+	CommentIgnore CommentMode = iota
+	//CommentDeliverSynthetic delivers each comment as an event of type
+	//CommentEventType with the comment as its Data. It does not affect the
+	//last event ID.
+	CommentDeliverSynthetic
+	//CommentResetLiveness treats comments as a sign of life, satisfying
+	//Options.FirstEventTimeout like an event.
+	CommentResetLiveness
+)
+
+//CommentEventType is the type of the events delivered for comments with
+//CommentDeliverSynthetic. A server can send events of this type too, e.g.
+//with "event::comment"; check Event.Synthetic to tell comments apart.
+const CommentEventType = ":comment"
+
+//comment handles the comment line, without its newline, according to
+//Options.CommentMode and Options.OnComment.
+func (s *session) comment(ctx context.Context, line []byte) error {
+	text := bytes.TrimPrefix(line[1:], []byte(" "))
+	if s.opts.OnComment != nil {
+		s.opts.OnComment(string(text))
+	}
+	switch s.opts.CommentMode {
+	case CommentDeliverSynthetic:
+		ev := &Event{URI: s.uri, Type: CommentEventType, Data: append([]byte(nil), text...), Header: s.header, Synthetic: true}
+		if err := s.deliver(ctx, ev); err != nil {
+			s.halt = err
+			return err
+		}
+	case CommentResetLiveness:
+		if s.firstEvent != nil {
+			s.firstEvent.Stop()
+		}
+	default:
+		s.logf(LogDebug, "comment, ignoring")
+	}
+	return nil
+}
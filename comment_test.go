@@ -0,0 +1,116 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const commentStream = ": hello\nid: 1\ndata: event 1\n\n:keepalive\n\ndata: event 2\n: inside\n\n"
+
+func TestCommentMode(t *testing.T) {
+	t.Run("ignore", func(t *testing.T) {
+		evCh := make(chan *Event, 5)
+		require.NoError(t, newSession("", Target{Chan: evCh}, nil).loop(context.Background(), strings.NewReader(commentStream)))
+		assert.Len(t, evCh, 2)
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		var (
+			comments []string
+			evCh     = make(chan *Event, 5)
+			opts     = &Options{OnComment: func(text string) { comments = append(comments, text) }}
+		)
+		require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(commentStream)))
+		assert.Len(t, evCh, 2)
+		assert.Equal(t, []string{"hello", "keepalive", "inside"}, comments)
+	})
+
+	t.Run("callback alongside another mode", func(t *testing.T) {
+		var (
+			comments []string
+			evCh     = make(chan *Event, 2)
+			opts     = &Options{CommentMode: CommentDeliverSynthetic, OnComment: func(text string) { comments = append(comments, text) }}
+		)
+		require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(": keepalive\n\n")))
+		assert.Equal(t, []string{"keepalive"}, comments)
+		assert.Len(t, evCh, 1)
+	})
+
+	t.Run("deliver synthetic", func(t *testing.T) {
+		var (
+			evCh = make(chan *Event, 5)
+			sess = newSession("uri", Target{Chan: evCh}, &Options{CommentMode: CommentDeliverSynthetic})
+		)
+		require.NoError(t, sess.loop(context.Background(), strings.NewReader(commentStream)))
+		close(evCh)
+		var events []*Event
+		for ev := range evCh {
+			events = append(events, ev)
+		}
+		assert.Equal(t, []*Event{
+			{URI: "uri", Type: CommentEventType, Data: []byte("hello"), Synthetic: true},
+			{URI: "uri", ID: "1", Data: []byte("event 1")},
+			{URI: "uri", Type: CommentEventType, Data: []byte("keepalive"), Synthetic: true},
+			{URI: "uri", Type: CommentEventType, Data: []byte("inside"), Synthetic: true},
+			{URI: "uri", ID: "1", Data: []byte("event 2")},
+		}, events)
+		assert.Equal(t, "1", sess.id)
+	})
+
+	t.Run("forged type", func(t *testing.T) {
+		evCh := make(chan *Event, 2)
+		opts := &Options{CommentMode: CommentDeliverSynthetic}
+		require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(": real\n\nevent::comment\ndata: forged\n\n")))
+		require.Len(t, evCh, 2)
+		real, forged := <-evCh, <-evCh
+		assert.Equal(t, CommentEventType, real.Type)
+		assert.True(t, real.Synthetic)
+		assert.Equal(t, CommentEventType, forged.Type)
+		assert.False(t, forged.Synthetic)
+	})
+
+	t.Run("counted in every mode", func(t *testing.T) {
+		for _, mode := range []CommentMode{CommentIgnore, CommentDeliverSynthetic, CommentResetLiveness} {
+			var (
+				comments int
+				stats    ParseStats
+				evCh     = make(chan *Event, 5)
+				opts     = &Options{
+					CommentMode: mode,
+					Metrics:     &Metrics{Comment: func() { comments++ }},
+					OnStats:     func(st ParseStats) { stats = st },
+				}
+			)
+			require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(commentStream)))
+			assert.Equal(t, 3, comments, "mode %d", mode)
+			assert.Equal(t, 3, stats.Comments, "mode %d", mode)
+		}
+	})
+
+	t.Run("reset liveness", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte(": ping\n"))
+			assert.NoError(t, err)
+			w.(http.Flusher).Flush()
+			time.Sleep(150 * time.Millisecond)
+			_, err = w.Write([]byte("data: event\n\n"))
+			assert.NoError(t, err)
+		}))
+		defer server.Close()
+
+		for mode, want := range map[CommentMode]error{CommentIgnore: ErrFirstEventTimeout, CommentResetLiveness: nil} {
+			evCh := make(chan *Event, 1)
+			opts := &Options{CommentMode: mode, FirstEventTimeout: 100 * time.Millisecond}
+			err := NotifyWithOptions(context.Background(), server.URL, false, evCh, opts)
+			assert.Equal(t, want, err, "mode %d", mode)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package sse
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//ReconnectCoordinator staggers the reconnects of the streams sharing it, by
+//setting it as their Options.Coordinator, so that they do not all hit a host
+//at once after an outage. Reconnects to the same host start at least Spacing
+//apart. The zero value does not stagger; Spacing must not be changed once the
+//coordinator is in use.
+type ReconnectCoordinator struct {
+	Spacing time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time // earliest start of the next reconnect per host
+}
+
+//wait blocks until it is the turn of a stream to reconnect to host or ctx is
+//done.
+func (c *ReconnectCoordinator) wait(ctx context.Context, host string) {
+	c.mu.Lock()
+	if c.next == nil {
+		c.next = make(map[string]time.Time)
+	}
+	now := time.Now()
+	at := c.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	c.next[host] = at.Add(c.Spacing)
+	c.mu.Unlock()
+
+	t := time.NewTimer(at.Sub(now))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,59 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconnectCoordinator(t *testing.T) {
+	const spacing = 100 * time.Millisecond
+
+	var (
+		mu         sync.Mutex
+		connected  = make(map[string]bool)
+		reconnects []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		stream := r.URL.Query().Get("stream")
+		if connected[stream] {
+			reconnects = append(reconnects, time.Now())
+			w.WriteHeader(204)
+			return
+		}
+		connected[stream] = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		wg   sync.WaitGroup
+		opts = &Options{Coordinator: &ReconnectCoordinator{Spacing: spacing}}
+	)
+	for _, stream := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := NotifyWithOptions(context.Background(), server.URL+"?stream="+stream, true, make(chan *Event, 1), opts)
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, reconnects, 3)
+	sort.Slice(reconnects, func(i, j int) bool { return reconnects[i].Before(reconnects[j]) })
+	for i := 1; i < len(reconnects); i++ {
+		assert.InDelta(t, spacing, reconnects[i].Sub(reconnects[i-1]), float64(30*time.Millisecond))
+	}
+}
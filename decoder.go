@@ -0,0 +1,115 @@
+package sse
+
+import (
+	"io"
+	"time"
+)
+
+//Decoder reads events from an event stream held by any io.Reader, such as a
+//file or a pipe, without the HTTP machinery of Notify.
+type Decoder struct {
+	fr   *fieldReader
+	id   string
+	wait time.Duration
+}
+
+//NewDecoder returns a Decoder reading from r. If r is nil, decoding fails
+//with ErrNilReader.
+func NewDecoder(r io.Reader) *Decoder {
+	if r == nil {
+		return &Decoder{}
+	}
+	return &Decoder{fr: newFieldReader(r)}
+}
+
+//Decode returns the next event of the stream, or io.EOF at its end. An event
+//that is not terminated by a blank line before the end is discarded.
+func (d *Decoder) Decode() (*Event, error) {
+	ev := &Event{}
+	if err := d.DecodeInto(ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+//DecodeInto is like Decode, but fills ev, overwriting its ID, Type and Data.
+//The Data slice is truncated and appended to, reusing its capacity, so that
+//decoding into the same Event repeatedly does not allocate; the caller must
+//copy Data before the next call if it needs to retain it.
+func (d *Decoder) DecodeInto(ev *Event) error {
+	_, err := d.decode(ev, false)
+	return err
+}
+
+//decode is DecodeInto, but if idBlocks is true it also returns at the end of
+//a block that only has id: fields, with just ev.ID set and event false.
+func (d *Decoder) decode(ev *Event, idBlocks bool) (event bool, err error) {
+	var (
+		typ     = ev.Type // reused when the next event has the same type
+		started bool
+		idField bool // whether the block has an id: field
+	)
+	if d.fr == nil {
+		return false, ErrNilReader
+	}
+	ev.Type, ev.Data = "", ev.Data[:0]
+
+	for {
+		f, err := d.fr.next()
+		if err != nil {
+			return false, err
+		}
+
+		if f.blank() {
+			if !started && idBlocks && idField {
+				ev.ID = d.id
+				return false, nil
+			}
+			if !started {
+				continue
+			}
+			if len(ev.Data) != 0 { // remove trailing \n
+				ev.Data = ev.Data[:len(ev.Data)-1]
+			}
+			ev.ID = d.id
+			return true, nil
+		}
+		if f.comment() {
+			continue
+		}
+
+		val := f.val
+		switch string(f.name) {
+		case rName:
+			wait, err := f.retry()
+			if err != nil {
+				continue // ignored, as in Notify
+			}
+			d.wait = wait
+		case iName:
+			if !f.validID() {
+				continue // ignored, as in Notify
+			}
+			if string(val) != d.id {
+				d.id = string(val)
+			}
+			idField = true
+		case eName:
+			if string(val) == typ {
+				ev.Type = typ
+			} else {
+				ev.Type = string(val)
+			}
+			started = true
+		case dName:
+			ev.Data = append(append(ev.Data, val...), '\n')
+			started = true
+		}
+	}
+}
+
+//Retry returns the reconnection time most recently sent by the stream through
+//a retry: field, or 0 if it has not sent one.
+func (d *Decoder) Retry() time.Duration {
+	return d.wait
+}
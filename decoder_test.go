@@ -0,0 +1,127 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	for name, stream := range map[string]string{
+		"specStream1":        specStream1,
+		"specStream2":        specStream2,
+		"specStream3":        specStream3,
+		"nameStream":         nameStream,
+		"invalidInputStream": invalidInputStream,
+		"idStream":           idStream,
+		"retryStream":        retryStream,
+		"urlStream":          urlStream,
+		"tabStream":          tabStream,
+		"bareFieldStream":    bareFieldStream,
+		"crlfBlankStream":    crlfBlankStream,
+		"bomStream":          bomStream,
+		"nulIDStream":        nulIDStream,
+		"crStream":           crStream,
+		"crlfStream":         crlfStream,
+		"mixedStream":        mixedStream,
+	} {
+		t.Run(name, func(t *testing.T) {
+			// loop is the reference for what the stream contains
+			evCh := make(chan *Event, 10)
+			require.NoError(t, newSession("", Target{Chan: evCh}, nil).loop(context.Background(), strings.NewReader(stream)))
+			close(evCh)
+			var want []*Event
+			for event := range evCh {
+				want = append(want, event)
+			}
+
+			var (
+				got []*Event
+				dec = NewDecoder(strings.NewReader(stream))
+			)
+			for {
+				ev, err := dec.Decode()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, ev)
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDecoderRetry(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(retryStream))
+	_, err := dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, 2000*time.Millisecond, dec.Retry())
+}
+
+func TestDecoderLongLine(t *testing.T) {
+	data := strings.Repeat("x", 10000) // longer than the bufio buffer
+	ev, err := NewDecoder(strings.NewReader("data: " + data + "\n\n")).Decode()
+	require.NoError(t, err)
+	assert.Equal(t, data, string(ev.Data))
+}
+
+func TestDecodeInto(t *testing.T) {
+	var (
+		dec = NewDecoder(strings.NewReader("id: 1\nevent: a\ndata: first, long\n\ndata: second\n\n"))
+		ev  Event
+	)
+
+	require.NoError(t, dec.DecodeInto(&ev))
+	assert.Equal(t, Event{ID: "1", Type: "a", Data: []byte("first, long")}, ev)
+	data := ev.Data
+
+	require.NoError(t, dec.DecodeInto(&ev))
+	assert.Equal(t, Event{ID: "1", Data: []byte("second")}, ev)
+	assert.Equal(t, &data[0], &ev.Data[0], "Data was reallocated")
+
+	assert.Equal(t, io.EOF, dec.DecodeInto(&ev))
+}
+
+func TestDecodeIntoAllocs(t *testing.T) {
+	var (
+		dec = NewDecoder(bytes.NewReader(bytes.Repeat([]byte("event: tick\ndata: hello world\n\n"), 1000)))
+		ev  Event
+	)
+	require.NoError(t, dec.DecodeInto(&ev)) // warm up
+
+	allocs := testing.AllocsPerRun(500, func() {
+		if err := dec.DecodeInto(&ev); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func BenchmarkDecodeInto(b *testing.B) {
+	var (
+		block  = []byte("event: tick\nid: 1\ndata: hello world\n\n")
+		stream = bytes.Repeat(block, 1000)
+		r      = bytes.NewReader(stream)
+		dec    = NewDecoder(r)
+		ev     Event
+	)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(block)))
+	for i := 0; i < b.N; i++ {
+		err := dec.DecodeInto(&ev)
+		if err == io.EOF {
+			r.Reset(stream)
+			err = dec.DecodeInto(&ev)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
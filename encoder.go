@@ -0,0 +1,33 @@
+package sse
+
+import (
+	"io"
+	"net/http"
+)
+
+//Encoder writes events to any io.Writer in the event stream format, the
+//counterpart of Decoder. Unlike Writer it does not set headers nor flush each
+//event; call Flush to do so.
+type Encoder struct {
+	w io.Writer
+}
+
+//NewEncoder returns an Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+//Encode writes ev as an event: its Type and ID, if non-empty, one data: line
+//per line of Data and a terminating blank line. It returns an error if the
+//ID or Type contain a line break.
+func (e *Encoder) Encode(ev *Event) error {
+	return writeEvent(e.w, ev, false)
+}
+
+//Flush flushes the underlying writer if it is an http.Flusher, and does
+//nothing otherwise.
+func (e *Encoder) Flush() {
+	if f, ok := e.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
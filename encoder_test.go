@@ -0,0 +1,48 @@
+package sse
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoder(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		enc    = NewEncoder(&buf)
+		events = []*Event{
+			{ID: "1", Type: "update", Data: []byte("line 1\nline 2")},
+			{Data: []byte("no id or type")},
+			{ID: "3"},
+		}
+	)
+	for _, ev := range events {
+		require.NoError(t, enc.Encode(ev))
+	}
+	assert.Equal(t, "id: 1\nevent: update\ndata: line 1\ndata: line 2\n\n"+
+		"data: no id or type\n\n"+
+		"id: 3\n\n", buf.String())
+
+	// the Decoder reads back what the Encoder wrote
+	got, err := NewDecoder(strings.NewReader(buf.String())).Decode()
+	require.NoError(t, err)
+	assert.Equal(t, events[0], got)
+
+	assert.Error(t, enc.Encode(&Event{Type: "bad\ntype"}))
+}
+
+func TestEncoderFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	enc := NewEncoder(rec)
+	require.NoError(t, enc.Encode(&Event{Data: []byte("hello")}))
+	assert.False(t, rec.Flushed)
+	enc.Flush()
+	assert.True(t, rec.Flushed)
+	assert.True(t, strings.HasSuffix(rec.Body.String(), "data: hello\n\n"))
+
+	NewEncoder(&bytes.Buffer{}).Flush() // not a Flusher
+}
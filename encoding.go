@@ -0,0 +1,105 @@
+package sse
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+//ContentDecoder returns a reader yielding the decoded form of r, which holds a
+//response body compressed with some content-coding.
+type ContentDecoder func(r io.Reader) (io.Reader, error)
+
+var (
+	contentDecodersMu sync.RWMutex
+	contentDecoders   = map[string]ContentDecoder{
+		"gzip":    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"deflate": decodeDeflate,
+	}
+)
+
+//decodeDeflate decodes a "deflate" body, which is meant to be zlib-wrapped
+//but is sent as a raw deflate stream by some servers.
+func decodeDeflate(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	hdr, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// a zlib header is a deflate method byte and a check making it a multiple of 31
+	if hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+//RegisterContentDecoder makes Notify decode responses whose Content-Encoding
+//is coding (compared case-insensitively) using fn, e.g. to add support for
+//"br" or "zstd" from an external library. Decoders for "gzip" and "deflate"
+//are registered by default; registering one again replaces it.
+func RegisterContentDecoder(coding string, fn ContentDecoder) {
+	contentDecodersMu.Lock()
+	defer contentDecodersMu.Unlock()
+	contentDecoders[strings.ToLower(coding)] = fn
+}
+
+//decodeBody undoes the content-codings listed in contentEncoding, which are
+//applied in the order listed and so are removed in reverse. An unsupported
+//coding fails right away, but the decoders are only created on the first
+//Read, as they read the body, e.g. a gzip header, and a server stalling
+//before that must not block connect.
+func decodeBody(body io.Reader, contentEncoding string) (io.Reader, error) {
+	if contentEncoding == "" {
+		return body, nil
+	}
+
+	contentDecodersMu.RLock()
+	defer contentDecodersMu.RUnlock()
+
+	d := &lazyDecoder{r: body}
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		if coding == "" || coding == "identity" {
+			continue
+		}
+		fn, ok := contentDecoders[coding]
+		if !ok {
+			return nil, fmt.Errorf("unsupported Content-Encoding: %s", coding)
+		}
+		d.codings = append(d.codings, coding)
+		d.fns = append(d.fns, fn)
+	}
+	if len(d.fns) == 0 {
+		return body, nil
+	}
+	return d, nil
+}
+
+//lazyDecoder applies its decoders to r on the first Read, see decodeBody.
+type lazyDecoder struct {
+	r       io.Reader
+	codings []string
+	fns     []ContentDecoder // left to apply, in order
+	err     error            // from applying them
+}
+
+func (d *lazyDecoder) Read(p []byte) (int, error) {
+	for len(d.fns) != 0 && d.err == nil {
+		r, err := d.fns[0](d.r)
+		if err != nil {
+			d.err = fmt.Errorf("error decoding %s body: %w", d.codings[0], err)
+			break
+		}
+		d.r, d.codings, d.fns = r, d.codings[1:], d.fns[1:]
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
@@ -0,0 +1,136 @@
+package sse
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rot13(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z':
+			c = 'a' + (c-'a'+13)%26
+		case c >= 'A' && c <= 'Z':
+			c = 'A' + (c-'A'+13)%26
+		}
+		out[i] = c
+	}
+	return out
+}
+
+func encodingServer(t *testing.T, coding string, body []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Encoding", coding)
+		_, err := w.Write(body)
+		assert.NoError(t, err)
+	}))
+}
+
+func TestContentDecoder(t *testing.T) {
+	RegisterContentDecoder("X-Rot13", func(r io.Reader) (io.Reader, error) {
+		b, err := io.ReadAll(r)
+		return bytes.NewReader(rot13(b)), err
+	})
+	defer func() {
+		contentDecodersMu.Lock()
+		delete(contentDecoders, "x-rot13")
+		contentDecodersMu.Unlock()
+	}()
+
+	server := encodingServer(t, "x-rot13", rot13([]byte("data: event 1\n\n")))
+	defer server.Close()
+
+	evCh := make(chan *Event, 1)
+	require.NoError(t, Notify(context.Background(), server.URL, false, evCh))
+	require.Len(t, evCh, 1)
+	assert.Equal(t, []byte("event 1"), (<-evCh).Data)
+}
+
+func TestContentDecoderCompressed(t *testing.T) {
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+		zw := newWriter(&buf)
+		_, err := zw.Write([]byte("data: event 1\n\n"))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+	t.Run("gzip", func(t *testing.T) {
+		testCompressed(t, "gzip", compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }))
+	})
+	t.Run("deflate", func(t *testing.T) {
+		testCompressed(t, "deflate", compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }))
+	})
+	t.Run("raw deflate", func(t *testing.T) {
+		testCompressed(t, "deflate", compress(func(w io.Writer) io.WriteCloser {
+			fw, err := flate.NewWriter(w, flate.DefaultCompression)
+			require.NoError(t, err)
+			return fw
+		}))
+	})
+}
+
+func testCompressed(t *testing.T, coding string, body []byte) {
+	server := encodingServer(t, coding, body)
+	defer server.Close()
+
+	// Setting Accept-Encoding ourselves stops the transport from transparently
+	// decompressing the response.
+	defaultGetReq := GetReq
+	defer func() { GetReq = defaultGetReq }()
+	GetReq = func(ctx context.Context, verb, uri string) (*http.Request, error) {
+		req, err := defaultGetReq(ctx, verb, uri)
+		if err == nil {
+			req.Header.Set("Accept-Encoding", coding)
+		}
+		return req, err
+	}
+
+	evCh := make(chan *Event, 1)
+	require.NoError(t, Notify(context.Background(), server.URL, false, evCh))
+	require.Len(t, evCh, 1)
+	assert.Equal(t, []byte("event 1"), (<-evCh).Data)
+}
+
+func TestContentDecoderUnsupported(t *testing.T) {
+	server := encodingServer(t, "x-unknown", []byte("data: event 1\n\n"))
+	defer server.Close()
+
+	err := Notify(context.Background(), server.URL, false, make(chan *Event, 1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported Content-Encoding: x-unknown")
+}
+
+func TestContentDecoderLazy(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-release // stall before the gzip header
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// reading the gzip header is up to ReadTimeout, not ConnectTimeout
+	err := NotifyWithOptions(context.Background(), server.URL, false, make(chan *Event, 1), &Options{
+		Header:         http.Header{"Accept-Encoding": {"gzip"}},
+		ConnectTimeout: 50 * time.Millisecond,
+		ReadTimeout:    200 * time.Millisecond,
+	})
+	assert.ErrorIs(t, err, ErrReadTimeout)
+}
@@ -0,0 +1,47 @@
+package sse
+
+import "errors"
+
+//The sentinel errors of the package. They are returned as is or wrapped, so
+//compare with errors.Is.
+var (
+	//ErrNilChan is returned by Notify and the other entry points if they are
+	//passed a nil channel or callback.
+	ErrNilChan = errors.New("nil channel given")
+
+	//ErrNilReader is returned by the Read and Decode methods of a Reader or
+	//Decoder created for a nil io.Reader.
+	ErrNilReader = errors.New("nil reader given")
+
+	//ErrTargetConflict is returned by NotifyTarget if it is given both a
+	//channel and a callback.
+	ErrTargetConflict = errors.New("both channel and callback given")
+
+	//ErrConnectTimeout is the error with which a connection attempt fails
+	//when it gets no response within Options.ConnectTimeout.
+	ErrConnectTimeout = errors.New("no response received within connect timeout")
+
+	//ErrFirstEventTimeout is the error with which a connection is dropped
+	//when it delivers no event within Options.FirstEventTimeout.
+	ErrFirstEventTimeout = errors.New("no event received within first event timeout")
+
+	//ErrReadTimeout is the error with which a connection is dropped when no
+	//line arrives on it within Options.ReadTimeout.
+	ErrReadTimeout = errors.New("no line received within read timeout")
+
+	//ErrEventTooLarge matches the *AbuseError returned for an event exceeding
+	//Options.MaxEventBytes or a line exceeding Options.MaxLineSize.
+	ErrEventTooLarge = errors.New("event too large")
+
+	//ErrSkipEvent can be returned by the decode function of NotifyTyped to
+	//skip an event instead of stopping the stream.
+	ErrSkipEvent = errors.New("skip event")
+
+	//ErrStreamClosed is returned by Stream.Err once the stream has been
+	//stopped by Stream.Close.
+	ErrStreamClosed = errors.New("stream closed")
+
+	//ErrNoFlusher is returned by NewWriter if the http.ResponseWriter cannot
+	//be flushed, in which case events would be buffered instead of sent.
+	ErrNoFlusher = errors.New("response writer does not support flushing")
+)
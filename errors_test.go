@@ -0,0 +1,77 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrNilChan(t *testing.T) {
+	assert.ErrorIs(t, Notify(context.Background(), "http://example.com", false, nil), ErrNilChan)
+	assert.ErrorIs(t, NotifyFunc(context.Background(), "http://example.com", false, nil), ErrNilChan)
+	assert.ErrorIs(t, NotifyTarget(context.Background(), "http://example.com", false, Target{}, nil), ErrNilChan)
+}
+
+func TestErrNilReader(t *testing.T) {
+	_, err := NewReader(nil).Read()
+	assert.ErrorIs(t, err, ErrNilReader)
+	_, err = NewDecoder(nil).Decode()
+	assert.ErrorIs(t, err, ErrNilReader)
+}
+
+func TestErrTargetConflict(t *testing.T) {
+	target := Target{Chan: make(chan *Event), Func: func(*Event) error { return nil }}
+	assert.ErrorIs(t, NotifyTarget(context.Background(), "http://example.com", false, target, nil), ErrTargetConflict)
+}
+
+func TestRequestErrorUnwraps(t *testing.T) {
+	errBuild := errors.New("no credentials")
+	defaultGetReq := GetReq
+	defer func() { GetReq = defaultGetReq }()
+	GetReq = func(ctx context.Context, verb, uri string) (*http.Request, error) {
+		return nil, errBuild
+	}
+
+	err := Notify(context.Background(), "http://example.com", false, make(chan *Event))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errBuild)
+	assert.Contains(t, err.Error(), "error getting sse request")
+}
+
+func TestTransportErrorUnwraps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // never respond
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := Notify(ctx, server.URL, false, make(chan *Event))
+
+	var (
+		transportErr *TransportError
+		urlErr       *url.Error
+	)
+	require.ErrorAs(t, err, &transportErr)
+	assert.ErrorAs(t, err, &urlErr, "the chain of the Client.Do error is kept")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTransportErrorCause(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	err := NotifyWithOptions(context.Background(), server.URL, false, make(chan *Event), &Options{ConnectTimeout: 50 * time.Millisecond})
+	var urlErr *url.Error
+	assert.ErrorIs(t, err, ErrConnectTimeout)
+	assert.ErrorAs(t, err, &urlErr)
+}
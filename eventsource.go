@@ -0,0 +1,167 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+//ReadyState is the state of the connection of an EventSource, with the values
+//of the browser API's readyState.
+type ReadyState int32
+
+const (
+	//Connecting is the state while connecting or waiting to reconnect.
+	Connecting ReadyState = iota
+	//Open is the state while receiving events.
+	Open
+	//Closed is the state once the EventSource has stopped for good.
+	Closed
+)
+
+//EventSource receives a stream in the background and dispatches its events
+//to listeners by type, like the browser's EventSource. It reconnects after
+//the stream ends, the connection is lost or cannot be made, e.g. while the
+//server restarts; a rejected request, such as one answered with a status
+//other than 200 OK, closes it.
+type EventSource struct {
+	mu        sync.Mutex
+	listeners map[string][]func(*Event)
+	onMessage func(*Event)
+	onError   func(error)
+
+	state  atomic.Int32
+	cancel context.CancelCauseFunc
+	done   chan struct{} // closed once the stream has stopped
+}
+
+//NewEventSource connects to the stream at uri in a new goroutine and returns
+//immediately. Events that arrive before a listener for their type is added
+//are dropped, so add listeners right away.
+func NewEventSource(uri string) *EventSource {
+	return NewEventSourceWithOptions(uri, nil)
+}
+
+//NewEventSourceWithOptions is like NewEventSource, but uses the additional
+//options given. Its OnConnect, OnReconnect and Metrics are still called, and
+//its ShouldReconnect is consulted for errors other than a *TransportError,
+//after which the EventSource always reconnects.
+func NewEventSourceWithOptions(uri string, opts *Options) *EventSource {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	es := &EventSource{listeners: map[string][]func(*Event){}, cancel: cancel, done: make(chan struct{})}
+
+	onConnect := o.OnConnect
+	o.OnConnect = func(res *http.Response) {
+		es.state.Store(int32(Open))
+		if onConnect != nil {
+			onConnect(res)
+		}
+	}
+	m := Metrics{}
+	if o.Metrics != nil {
+		m = *o.Metrics
+	}
+	reconnect := m.Reconnect
+	m.Reconnect = func(attempt int, cause error) {
+		es.state.Store(int32(Connecting))
+		if cause != nil {
+			es.error(cause)
+		}
+		if reconnect != nil {
+			reconnect(attempt, cause)
+		}
+	}
+	o.Metrics = &m
+	// like the browser's, only a rejected response closes it for good
+	shouldReconnect := o.ShouldReconnect
+	o.ShouldReconnect = func(err error) bool {
+		var transportErr *TransportError
+		if errors.As(err, &transportErr) {
+			return true
+		}
+		return shouldReconnect != nil && shouldReconnect(err)
+	}
+
+	go func() {
+		defer close(es.done)
+		err := NotifyTarget(ctx, uri, true, Target{Func: es.dispatch}, &o)
+		es.state.Store(int32(Closed))
+		cancel(nil)
+		if err != nil && !errors.Is(err, ErrStreamClosed) {
+			es.error(err)
+		}
+	}()
+	return es
+}
+
+//AddEventListener calls fn for each event of type typ, in the order the
+//listeners were added. Events without an event: field have the type
+//"message", see MessageEventType.
+func (es *EventSource) AddEventListener(typ string, fn func(*Event)) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.listeners[typ] = append(es.listeners[typ], fn)
+}
+
+//OnMessage sets the handler of events of type "message", which is called
+//after their listeners.
+func (es *EventSource) OnMessage(fn func(*Event)) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.onMessage = fn
+}
+
+//OnError sets the handler called with the error each time the connection is
+//lost or a reconnect fails, before reconnecting, and with the error that
+//closes the EventSource, if any.
+func (es *EventSource) OnError(fn func(error)) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.onError = fn
+}
+
+//ReadyState returns the current state of the connection.
+func (es *EventSource) ReadyState() ReadyState {
+	return ReadyState(es.state.Load())
+}
+
+//Close stops the EventSource and waits until no more handlers will be
+//called. Calling it more than once is safe.
+func (es *EventSource) Close() {
+	es.cancel(ErrStreamClosed)
+	<-es.done
+}
+
+func (es *EventSource) dispatch(ev *Event) error {
+	typ := ev.Type
+	if typ == "" {
+		typ = MessageEventType
+	}
+	es.mu.Lock()
+	listeners := es.listeners[typ]
+	onMessage := es.onMessage
+	es.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(ev)
+	}
+	if typ == MessageEventType && onMessage != nil {
+		onMessage(ev)
+	}
+	return nil
+}
+
+func (es *EventSource) error(err error) {
+	es.mu.Lock()
+	onError := es.onError
+	es.mu.Unlock()
+	if onError != nil {
+		onError(err)
+	}
+}
@@ -0,0 +1,187 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventSource(t *testing.T) {
+	ready := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-ready // wait for the listeners
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: m1\n\nevent: update\ndata: u1\n\nevent: other\ndata: o1\n\nevent: message\ndata: m2\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var (
+		mu       sync.Mutex
+		routed   []string
+		received = make(chan struct{}, 8)
+		record   = func(prefix string) func(*Event) {
+			return func(ev *Event) {
+				mu.Lock()
+				routed = append(routed, prefix+string(ev.Data))
+				mu.Unlock()
+				received <- struct{}{}
+			}
+		}
+	)
+	es := NewEventSource(server.URL)
+	assert.Equal(t, Connecting, es.ReadyState())
+	es.AddEventListener("update", record("update:"))
+	es.AddEventListener("message", record("listener:"))
+	es.OnMessage(record("onmessage:"))
+	close(ready)
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("events not dispatched")
+		}
+	}
+	assert.Equal(t, Open, es.ReadyState())
+
+	es.Close()
+	assert.Equal(t, Closed, es.ReadyState())
+	es.Close() // closing again is safe
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"listener:m1", "onmessage:m1", "update:u1", "listener:m2", "onmessage:m2"}, routed)
+}
+
+func TestEventSourceError(t *testing.T) {
+	ready := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-ready
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	errs := make(chan error, 1)
+	es := NewEventSource(server.URL)
+	defer es.Close()
+	es.OnError(func(err error) { errs <- err })
+	close(ready)
+
+	select {
+	case err := <-errs:
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+	case <-time.After(time.Second):
+		t.Fatal("OnError not called")
+	}
+	es.Close()
+	assert.Equal(t, Closed, es.ReadyState())
+}
+
+func TestEventSourceReconnect(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	ready := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-ready
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		if n == 1 {
+			_, err := w.Write([]byte("retry: 10\ndata: first\n\n"))
+			assert.NoError(t, err)
+			return
+		}
+		_, err := w.Write([]byte("data: second\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	data := make(chan string, 2)
+	es := NewEventSource(server.URL)
+	defer es.Close()
+	es.OnMessage(func(ev *Event) { data <- string(ev.Data) })
+	close(ready)
+
+	for _, want := range []string{"first", "second"} {
+		select {
+		case got := <-data:
+			assert.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatal("no event after reconnecting")
+		}
+	}
+}
+
+func TestEventSourceServerDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: back\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	// the first attempt fails as if the server were restarting
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		attempts++
+		n := attempts
+		mu.Unlock()
+		if n == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+
+	var (
+		ready = make(chan struct{})
+		errs  = make(chan error, 1)
+		data  = make(chan string, 1)
+	)
+	es := NewEventSourceWithOptions(server.URL, &Options{
+		Client:     client,
+		PreConnect: func(context.Context) error { <-ready; return nil },
+	})
+	defer es.Close()
+	es.OnError(func(err error) { errs <- err })
+	es.OnMessage(func(ev *Event) { data <- string(ev.Data) })
+	close(ready)
+
+	select {
+	case err := <-errs:
+		var transportErr *TransportError
+		assert.ErrorAs(t, err, &transportErr)
+	case <-time.After(2 * time.Second): // after the reconnection time
+		t.Fatal("OnError not called")
+	}
+	select {
+	case got := <-data:
+		assert.Equal(t, "back", got)
+	case <-time.After(time.Second):
+		t.Fatal("did not reconnect")
+	}
+	assert.Equal(t, Open, es.ReadyState())
+}
@@ -0,0 +1,102 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+//delayPrefix marks a comment line in a recorded stream holding the time that
+//passed before the next line arrived, e.g. ": delay 250ms". Being a comment,
+//it is ignored by the parser.
+var delayPrefix = []byte(": delay ")
+
+//FileSource opens a recorded event stream for replay. If pace is true, reading
+//pauses for the given duration at each ": delay <duration>" comment, so that
+//the replay reproduces the timing of the original stream.
+func FileSource(path string, pace bool) (io.ReadCloser, error) {
+	return fileSource(context.Background(), path, pace, newSession(path, Target{}, nil))
+}
+
+//NotifyFile replays the recorded event stream at path, sending each Event down
+//evCh as Notify would for a live stream, until the end of the file is
+//reached. See FileSource for the meaning of pace.
+func NotifyFile(ctx context.Context, path string, pace bool, evCh chan<- *Event) error {
+	if evCh == nil {
+		return ErrNilChan
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s := newSession(path, Target{Chan: evCh}, &Options{})
+	r, err := fileSource(ctx, path, pace, s)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return causeOf(ctx, s.loop(ctx, r))
+}
+
+//fileSource is FileSource, logging through s.
+func fileSource(ctx context.Context, path string, pace bool, s *session) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !pace {
+		return f, nil
+	}
+	return &pacedReader{ctx: ctx, f: f, br: bufio.NewReader(f), s: s}, nil
+}
+
+//pacedReader hands out a recorded stream line by line, sleeping whenever it
+//comes across a delay comment.
+type pacedReader struct {
+	ctx  context.Context
+	f    *os.File
+	br   *bufio.Reader
+	line []byte
+	s    *session // for logging
+}
+
+func (p *pacedReader) Read(b []byte) (int, error) {
+	if len(p.line) == 0 {
+		line, err := p.br.ReadBytes('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		if bytes.HasPrefix(line, delayPrefix) {
+			d, perr := time.ParseDuration(string(bytes.TrimSpace(line[len(delayPrefix):])))
+			if perr != nil {
+				p.s.logf(LogInfo, "failed to parse delay comment: %s, ignoring", perr.Error())
+			} else if err := p.sleep(d); err != nil {
+				return 0, err
+			}
+		}
+		p.line = line
+	}
+
+	n := copy(b, p.line)
+	p.line = p.line[n:]
+	return n, nil
+}
+
+func (p *pacedReader) sleep(d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (p *pacedReader) Close() error {
+	return p.f.Close()
+}
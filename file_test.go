@@ -0,0 +1,108 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var recordedStream = `data: event 1
+
+: delay 100ms
+data: event 2
+
+: delay 100ms
+data: event 3
+
+`
+
+func TestNotifyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.sse")
+	require.NoError(t, os.WriteFile(path, []byte(recordedStream), 0o600))
+
+	var (
+		events []*Event
+		times  []time.Time
+		evCh   = make(chan *Event)
+		wg     = sync.WaitGroup{}
+		start  = time.Now()
+	)
+
+	wg.Add(2)
+	go func() {
+		assert.NoError(t, NotifyFile(context.Background(), path, true, evCh))
+		close(evCh)
+		wg.Done()
+	}()
+	go func() {
+		for event := range evCh {
+			events = append(events, event)
+			times = append(times, time.Now())
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+
+	require.Equal(t,
+		[]*Event{
+			{URI: path, Data: []byte("event 1")},
+			{URI: path, Data: []byte("event 2")},
+			{URI: path, Data: []byte("event 3")},
+		},
+		events,
+	)
+	assert.Less(t, times[0].Sub(start), 50*time.Millisecond)
+	assert.InDelta(t, 100*time.Millisecond, times[1].Sub(times[0]), float64(50*time.Millisecond))
+	assert.InDelta(t, 100*time.Millisecond, times[2].Sub(times[1]), float64(50*time.Millisecond))
+}
+
+func TestFileSourceUnpaced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.sse")
+	require.NoError(t, os.WriteFile(path, []byte(recordedStream), 0o600))
+
+	r, err := FileSource(path, false)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var (
+		events []*Event
+		evCh   = make(chan *Event, 3)
+		start  = time.Now()
+	)
+	require.NoError(t, newSession(path, Target{Chan: evCh}, &Options{}).loop(context.Background(), r))
+	close(evCh)
+	for event := range evCh {
+		events = append(events, event)
+	}
+
+	assert.Len(t, events, 3)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestFileSourceDelayLogLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.sse")
+	require.NoError(t, os.WriteFile(path, []byte(": delay soon\ndata: event\n\n"), 0o600))
+
+	var logs bytes.Buffer
+	Logger.SetOutput(&logs)
+	defer Logger.SetOutput(io.Discard)
+	for _, level := range []LogLevel{LogError, LogInfo} {
+		r, err := fileSource(context.Background(), path, true, newSession(path, Target{}, &Options{LogLevel: level}))
+		require.NoError(t, err)
+		_, err = io.ReadAll(r)
+		assert.NoError(t, err)
+		r.Close()
+		if level == LogError {
+			assert.Empty(t, logs.String())
+		}
+	}
+	assert.Contains(t, logs.String(), "failed to parse delay comment")
+}
@@ -0,0 +1,106 @@
+package sse
+
+import "time"
+
+//GroupByID reads events from in and delivers them grouped by ID, so that the
+//updates to one entity arriving close together can be processed at once. A
+//group is flushed window after the first event of the group was received,
+//holding every event received meanwhile in order of arrival, keyed by ID.
+//Remaining events are flushed when in is closed, after which the returned
+//channel is closed too.
+func GroupByID(in <-chan *Event, window time.Duration) <-chan map[string][]*Event {
+	out := make(chan map[string][]*Event)
+	go func() {
+		defer close(out)
+
+		var (
+			group map[string][]*Event
+			timer = time.NewTimer(window)
+			flush <-chan time.Time
+		)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					if group != nil {
+						out <- group
+					}
+					return
+				}
+				if group == nil {
+					group = make(map[string][]*Event)
+					timer.Reset(window)
+					flush = timer.C
+				}
+				group[ev.ID] = append(group[ev.ID], ev)
+			case <-flush:
+				out <- group
+				group, flush = nil, nil
+			}
+		}
+	}()
+	return out
+}
+
+//Coalesce reads events from in and merges runs of consecutive events of the
+//same type into one, for append-style streams where many small events are
+//cheaper to process together. The merged event has the Data of the events
+//joined by newlines, as if they had been data: lines of a single event, and
+//the ID and other fields of the last of them. A run is delivered when an event
+//of another type arrives, window after its first event, once its Data
+//reaches maxBytes if positive, and when in is closed, after which the
+//returned channel is closed too.
+func Coalesce(in <-chan *Event, window time.Duration, maxBytes int) <-chan *Event {
+	out := make(chan *Event)
+	go func() {
+		defer close(out)
+
+		var (
+			run   *Event
+			timer = time.NewTimer(window)
+			flush <-chan time.Time
+		)
+		timer.Stop()
+		defer timer.Stop()
+
+		deliver := func() {
+			timer.Stop()
+			out <- run
+			run, flush = nil, nil
+		}
+		for {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					if run != nil {
+						deliver()
+					}
+					return
+				}
+				if run != nil && run.Type != ev.Type {
+					deliver()
+				}
+				if run == nil {
+					run = &Event{}
+					*run = *ev
+					run.Data = append([]byte(nil), ev.Data...)
+					timer.Reset(window)
+					flush = timer.C
+				} else {
+					data := append(append(run.Data, '\n'), ev.Data...)
+					*run = *ev
+					run.Data = data
+				}
+				if maxBytes > 0 && len(run.Data) >= maxBytes {
+					deliver()
+				}
+			case <-flush:
+				deliver()
+			}
+		}
+	}()
+	return out
+}
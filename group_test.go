@@ -0,0 +1,73 @@
+package sse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupByID(t *testing.T) {
+	var (
+		in     = make(chan *Event)
+		groups = GroupByID(in, 50*time.Millisecond)
+		a1     = &Event{ID: "a", Data: []byte("1")}
+		b1     = &Event{ID: "b", Data: []byte("1")}
+		a2     = &Event{ID: "a", Data: []byte("2")}
+		b2     = &Event{ID: "b", Data: []byte("2")}
+		a3     = &Event{ID: "a", Data: []byte("3")}
+	)
+
+	for _, ev := range []*Event{a1, b1, a2, b2} {
+		in <- ev
+	}
+	select {
+	case group := <-groups:
+		assert.Equal(t, map[string][]*Event{"a": {a1, a2}, "b": {b1, b2}}, group)
+	case <-time.After(time.Second):
+		require.FailNow(t, "group was not flushed after the window")
+	}
+
+	in <- a3
+	close(in)
+	assert.Equal(t, map[string][]*Event{"a": {a3}}, <-groups)
+	_, ok := <-groups
+	assert.False(t, ok)
+}
+
+func TestCoalesce(t *testing.T) {
+	var (
+		in     = make(chan *Event)
+		merged = Coalesce(in, 50*time.Millisecond, 13)
+		events = []*Event{
+			{ID: "1", Type: "log", Data: []byte("line 1")},
+			{ID: "2", Type: "log", Data: []byte("line 2")},
+			{ID: "3", Type: "status", Data: []byte("ok")},
+			{ID: "4", Type: "log", Data: []byte("line 3")},
+			{ID: "5", Type: "log", Data: []byte("line 4")},
+			{ID: "6", Type: "log", Data: []byte("line 5")},
+		}
+	)
+	go func() {
+		for _, ev := range events {
+			in <- ev
+		}
+		time.Sleep(100 * time.Millisecond) // let the window pass
+		in <- &Event{ID: "7", Type: "log", Data: []byte("line 6")}
+		close(in)
+	}()
+
+	var got []*Event
+	for ev := range merged {
+		got = append(got, ev)
+	}
+	assert.Equal(t, []*Event{
+		{ID: "2", Type: "log", Data: []byte("line 1\nline 2")},
+		{ID: "3", Type: "status", Data: []byte("ok")},
+		{ID: "5", Type: "log", Data: []byte("line 3\nline 4")}, // maxBytes reached
+		{ID: "6", Type: "log", Data: []byte("line 5")},         // window passed
+		{ID: "7", Type: "log", Data: []byte("line 6")},
+	}, got)
+	assert.Equal(t, []byte("line 1"), events[0].Data, "input events are not modified")
+}
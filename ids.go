@@ -0,0 +1,65 @@
+package sse
+
+import "strconv"
+
+//IDOrder selects how Options.IDOrder compares the IDs of consecutive events
+//when checking that they increase.
+type IDOrder int
+
+const (
+	//IDOrderNone disables the check.
+	IDOrderNone IDOrder = iota
+	//IDOrderLexical compares IDs as strings.
+	IDOrderLexical
+	//IDOrderNumeric compares IDs as unsigned integers. IDs that are not
+	//numeric are not checked.
+	IDOrderNumeric
+)
+
+//increases reports whether id comes after prev. ok is false if o cannot
+//compare the two.
+func (o IDOrder) increases(prev, id string) (increasing, ok bool) {
+	switch o {
+	case IDOrderLexical:
+		return id > prev, true
+	case IDOrderNumeric:
+		p, err := strconv.ParseUint(prev, 10, 64)
+		if err != nil {
+			return false, false
+		}
+		i, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return false, false
+		}
+		return i > p, true
+	}
+	return false, false
+}
+
+//idWindow remembers the last IDs added to it, for Options.DedupWindow.
+type idWindow struct {
+	ids  []string        // ring buffer of the remembered IDs
+	next int             // index in ids of the next ID to add
+	seen map[string]bool // the IDs in ids
+}
+
+func newIDWindow(size int) *idWindow {
+	return &idWindow{ids: make([]string, 0, size), seen: make(map[string]bool, size)}
+}
+
+//add remembers id, forgetting the oldest ID if the window is full, unless it
+//is already remembered. It reports whether id was new.
+func (w *idWindow) add(id string) bool {
+	if w.seen[id] {
+		return false
+	}
+	if len(w.ids) < cap(w.ids) {
+		w.ids = append(w.ids, id)
+	} else {
+		delete(w.seen, w.ids[w.next])
+		w.ids[w.next] = id
+		w.next = (w.next + 1) % len(w.ids)
+	}
+	w.seen[id] = true
+	return true
+}
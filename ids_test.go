@@ -0,0 +1,107 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDOrder(t *testing.T) {
+	const stream = "id: 9\ndata: a\n\ndata: b\n\nid: 10\ndata: c\n\nid: 3\ndata: d\n\n"
+
+	tests := []struct {
+		name        string
+		order       IDOrder
+		regressions [][2]string
+	}{
+		{name: "none", order: IDOrderNone},
+		{name: "numeric", order: IDOrderNumeric, regressions: [][2]string{{"10", "3"}}},
+		{name: "lexical", order: IDOrderLexical, regressions: [][2]string{{"9", "10"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				regressions [][2]string
+				evCh        = make(chan *Event, 4)
+				opts        = &Options{
+					IDOrder: tt.order,
+					OnIDRegression: func(prev, id string) {
+						regressions = append(regressions, [2]string{prev, id})
+					},
+				}
+			)
+			require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+			assert.Len(t, evCh, 4)
+			assert.Equal(t, tt.regressions, regressions)
+		})
+	}
+}
+
+func TestIDOrderDedup(t *testing.T) {
+	var (
+		regressions [][2]string
+		evCh        = make(chan *Event, 4)
+		opts        = &Options{
+			IDOrder:     IDOrderNumeric,
+			DedupWindow: 8,
+			OnIDRegression: func(prev, id string) {
+				regressions = append(regressions, [2]string{prev, id})
+			},
+		}
+	)
+	stream := "id: 1\ndata: a\n\nid: 2\ndata: b\n\nid: 2\ndata: b\n\nid: 1\ndata: a\n\nid: 3\ndata: c\n\n"
+	require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+	assert.Len(t, evCh, 3)
+	assert.Empty(t, regressions, "replayed duplicates are dropped before the order is checked")
+}
+
+func TestIDWindow(t *testing.T) {
+	w := newIDWindow(2)
+	assert.True(t, w.add("1"))
+	assert.True(t, w.add("2"))
+	assert.False(t, w.add("1"))
+	assert.True(t, w.add("3")) // forgets 1
+	assert.True(t, w.add("1")) // forgets 2
+	assert.False(t, w.add("3"))
+	assert.True(t, w.add("2"))
+	assert.Len(t, w.seen, 2)
+}
+
+func TestDedupWindow(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 10\nid: myid\ndata: event 1\n\n"))
+			assert.NoError(t, err)
+		case 2:
+			// replays the event it was asked to resume after
+			assert.Equal(t, "myid", r.Header.Get("Last-Event-ID"))
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("id: myid\ndata: event 1\n\ndata: no id\n\nid: 2\ndata: event 2\n\n"))
+			assert.NoError(t, err)
+		default:
+			assert.Equal(t, "2", r.Header.Get("Last-Event-ID"))
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	evCh := make(chan *Event, 4)
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, &Options{DedupWindow: 8})
+	assert.Error(t, err)
+	close(evCh)
+	var data []string
+	for ev := range evCh {
+		data = append(data, string(ev.Data))
+	}
+	assert.Equal(t, []string{"event 1", "no id", "event 2"}, data)
+}
@@ -0,0 +1,36 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+//errStopIteration halts the stream when the body of a range over Iterate
+//returns early.
+var errStopIteration = errors.New("iteration stopped")
+
+//Iterate returns the events of the stream at uri as an iterator, for use with
+//a range loop. Events are read as the loop consumes them; leaving the loop
+//closes the connection. If the stream stops with an error, it is yielded as
+//the final pair with a nil event: e.g. a *StatusError for a rejected request,
+//a *TransportError, or the cause of ctx's cancellation.
+func Iterate(ctx context.Context, uri string, retry bool, opts *Options) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		err := NotifyTarget(ctx, uri, retry, Target{Func: func(ev *Event) error {
+			if !yield(ev, nil) {
+				return errStopIteration
+			}
+			return nil
+		}}, opts)
+		if err != nil && err != errStopIteration {
+			yield(nil, err)
+		}
+	}
+}
+
+//Events is like Iterate with default options, as Notify is to
+//NotifyWithOptions.
+func Events(ctx context.Context, uri string, retry bool) iter.Seq2[*Event, error] {
+	return Iterate(ctx, uri, retry, nil)
+}
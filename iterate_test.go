@@ -0,0 +1,98 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterate(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event 1\n\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		data []string
+		last error
+	)
+	for ev, err := range Iterate(context.Background(), server.URL, true, nil) {
+		if err != nil {
+			last = err
+			continue
+		}
+		data = append(data, string(ev.Data))
+	}
+	assert.Equal(t, []string{"event 1", "event 2"}, data)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, last, &statusErr)
+	assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+}
+
+func TestIterateBreak(t *testing.T) {
+	server := twoEventServer(t)
+	defer server.Close()
+
+	var n int
+	for ev, err := range Iterate(context.Background(), server.URL, true, nil) {
+		require.NoError(t, err)
+		require.NotNil(t, ev)
+		n++
+		break
+	}
+	assert.Equal(t, 1, n)
+}
+
+func TestIterateCancelCause(t *testing.T) {
+	var (
+		cause       = errors.New("shutting down")
+		ctx, cancel = context.WithCancelCause(context.Background())
+	)
+	defer cancel(nil)
+	server := twoEventServer(t)
+	defer server.Close()
+
+	var last error
+	for ev, err := range Iterate(ctx, server.URL, true, nil) {
+		if err != nil {
+			last = err
+			continue
+		}
+		if ev != nil {
+			cancel(cause)
+		}
+	}
+	assert.ErrorIs(t, last, cause)
+}
+
+func TestEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("id: 1\ndata: event 1\n\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var events []*Event
+	for ev, err := range Events(context.Background(), server.URL, false) {
+		require.NoError(t, err)
+		events = append(events, ev)
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, "1", events[1].ID)
+	assert.Equal(t, []byte("event 2"), events[1].Data)
+}
@@ -0,0 +1,39 @@
+package sse
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//LengthError is returned when the data of an event does not have the length
+//announced by its Options.LengthField field.
+type LengthError struct {
+	URI string
+	ID  string
+	//Want is the announced length, or -1 if it was not a valid number.
+	Want int
+	Got  int
+}
+
+func (e *LengthError) Error() string {
+	if e.Want < 0 {
+		return fmt.Sprintf("%s sent event %q with invalid length", e.URI, e.ID)
+	}
+	return fmt.Sprintf("%s sent event %q with %d bytes of data, announced %d", e.URI, e.ID, e.Got, e.Want)
+}
+
+//verifyLength checks the data of ev against length, the value of its length
+//field, if it has one.
+func (s *session) verifyLength(ev *Event, length string) error {
+	if length == "" {
+		return nil
+	}
+	want, err := strconv.Atoi(length)
+	if err != nil || want < 0 {
+		want = -1
+	}
+	if want != len(ev.Data) {
+		return &LengthError{URI: s.uri, ID: ev.ID, Want: want, Got: len(ev.Data)}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLengthField(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		delivered int
+		want      int
+		got       int
+	}{
+		{
+			name:      "matching",
+			stream:    "length: 5\ndata: hello\n\nlength: 9\ndata: hello\ndata: you\n\ndata: unchecked\n\n",
+			delivered: 3,
+		},
+		{
+			name:      "mismatch",
+			stream:    "length: 5\ndata: hello\n\nid: 2\nlength: 4\ndata: hello\n\n",
+			delivered: 1,
+			want:      4,
+			got:       5,
+		},
+		{
+			name:      "invalid",
+			stream:    "id: 2\nlength: five\ndata: hello\n\n",
+			delivered: 0,
+			want:      -1,
+			got:       5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evCh := make(chan *Event, 3)
+			err := newSession("uri", Target{Chan: evCh}, &Options{LengthField: "length"}).loop(context.Background(), strings.NewReader(tt.stream))
+			assert.Len(t, evCh, tt.delivered)
+			if tt.want == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var lengthErr *LengthError
+			require.True(t, errors.As(err, &lengthErr))
+			assert.Equal(t, &LengthError{URI: "uri", ID: "2", Want: tt.want, Got: tt.got}, lengthErr)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package sse
+
+import (
+	"fmt"
+	"time"
+)
+
+//AbuseError is returned when a stream exceeds one of the behavioural limits
+//configured in Options. The offending event is not delivered.
+type AbuseError struct {
+	URI string
+	//Metric names the limit that was exceeded, "events per second",
+	//"average event size", "event size" or "line size".
+	Metric string
+	Limit  float64
+	Value  float64
+}
+
+func (e *AbuseError) Error() string {
+	return fmt.Sprintf("%s exceeded %s limit: %g > %g", e.URI, e.Metric, e.Value, e.Limit)
+}
+
+//Is makes an *AbuseError for Options.MaxEventBytes or Options.MaxLineSize
+//match ErrEventTooLarge.
+func (e *AbuseError) Is(target error) bool {
+	return target == ErrEventTooLarge && (e.Metric == "event size" || e.Metric == "line size")
+}
+
+//OversizePolicy is what to do with an event whose data exceeds
+//Options.MaxEventBytes.
+type OversizePolicy int
+
+const (
+	//OversizeError aborts the connection with an *AbuseError. It is the
+	//default.
+	OversizeError OversizePolicy = iota
+	//OversizeTruncate delivers the event with its data cut short at the limit
+	//and Truncated set.
+	OversizeTruncate
+	//OversizeSkip drops the event and continues with the next one. The last
+	//event ID is not advanced to its ID.
+	OversizeSkip
+)
+
+//limiter enforces Options.MaxEventsPerSecond and Options.MaxAverageEventSize
+//over the events of a single connection.
+type limiter struct {
+	uri  string
+	opts *Options
+
+	windowStart time.Time
+	windowCount int
+
+	events int
+	bytes  int
+}
+
+//check accounts for ev and returns an *AbuseError if doing so exceeds a limit.
+func (l *limiter) check(ev *Event) error {
+	if max := l.opts.MaxEventsPerSecond; max > 0 {
+		now := time.Now()
+		if now.Sub(l.windowStart) >= time.Second {
+			l.windowStart, l.windowCount = now, 0
+		}
+		l.windowCount++
+		if l.windowCount > max {
+			return &AbuseError{URI: l.uri, Metric: "events per second", Limit: float64(max), Value: float64(l.windowCount)}
+		}
+	}
+	if max := l.opts.MaxAverageEventSize; max > 0 {
+		l.events++
+		l.bytes += len(ev.Data)
+		if avg := float64(l.bytes) / float64(l.events); avg > float64(max) {
+			return &AbuseError{URI: l.uri, Metric: "average event size", Limit: float64(max), Value: avg}
+		}
+	}
+	return nil
+}
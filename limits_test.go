@@ -0,0 +1,144 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbuseLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		opts      *Options
+		delivered int
+		metric    string
+		value     float64
+	}{
+		{
+			name:      "events per second",
+			stream:    "data: 1\n\ndata: 2\n\ndata: 3\n\ndata: 4\n\n",
+			opts:      &Options{MaxEventsPerSecond: 2},
+			delivered: 2,
+			metric:    "events per second",
+			value:     3,
+		},
+		{
+			name:      "average event size",
+			stream:    "data: ab\n\ndata: abcdefghij\n\n",
+			opts:      &Options{MaxAverageEventSize: 4},
+			delivered: 1,
+			metric:    "average event size",
+			value:     6,
+		},
+		{
+			name:      "within limits",
+			stream:    "data: ab\n\ndata: abcd\n\n",
+			opts:      &Options{MaxEventsPerSecond: 2, MaxAverageEventSize: 4},
+			delivered: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evCh := make(chan *Event, 10)
+			err := newSession("uri", Target{Chan: evCh}, tt.opts).loop(context.Background(), strings.NewReader(tt.stream))
+			close(evCh)
+			assert.Len(t, evCh, tt.delivered)
+
+			if tt.metric == "" {
+				require.NoError(t, err)
+				return
+			}
+			var abuseErr *AbuseError
+			require.True(t, errors.As(err, &abuseErr))
+			assert.Equal(t, tt.metric, abuseErr.Metric)
+			assert.Equal(t, tt.value, abuseErr.Value)
+			assert.Equal(t, "uri", abuseErr.URI)
+		})
+	}
+}
+
+func TestOversize(t *testing.T) {
+	const stream = "data: ab\n\nid: 2\ndata: abc\ndata: defg\ndata: hi\n\ndata: cd\n\n"
+
+	tests := []struct {
+		policy OversizePolicy
+		events []*Event
+		lastID string
+		err    bool
+	}{
+		{
+			policy: OversizeError,
+			events: []*Event{{Data: []byte("ab")}},
+			err:    true,
+		},
+		{
+			policy: OversizeTruncate,
+			events: []*Event{
+				{Data: []byte("ab")},
+				{ID: "2", Data: []byte("abc\nde"), Truncated: true},
+				{ID: "2", Data: []byte("cd")},
+			},
+			lastID: "2",
+		},
+		{
+			policy: OversizeSkip,
+			events: []*Event{
+				{Data: []byte("ab")},
+				{ID: "2", Data: []byte("cd")},
+			},
+			lastID: "2",
+		},
+	}
+
+	for _, tt := range tests {
+		var (
+			evCh = make(chan *Event, 3)
+			sess = newSession("", Target{Chan: evCh}, &Options{MaxEventBytes: 6, OnOversize: tt.policy})
+			err  = sess.loop(context.Background(), strings.NewReader(stream))
+		)
+		close(evCh)
+		var events []*Event
+		for ev := range evCh {
+			events = append(events, ev)
+		}
+		assert.Equal(t, tt.events, events, "policy %d", tt.policy)
+
+		if !tt.err {
+			assert.NoError(t, err)
+			assert.Equal(t, tt.lastID, sess.id)
+			continue
+		}
+		assert.ErrorIs(t, err, ErrEventTooLarge)
+		var abuseErr *AbuseError
+		require.ErrorAs(t, err, &abuseErr)
+		assert.Equal(t, "event size", abuseErr.Metric)
+		assert.Equal(t, float64(8), abuseErr.Value)
+	}
+}
+
+func TestMaxLineSize(t *testing.T) {
+	var (
+		long   = "data: " + strings.Repeat("x", 10000) + "\n\n"
+		stream = "data: short\n\n" + long
+		evCh   = make(chan *Event, 2)
+	)
+	err := newSession("uri", Target{Chan: evCh}, &Options{MaxLineSize: 1024}).loop(context.Background(), strings.NewReader(stream))
+	assert.ErrorIs(t, err, ErrEventTooLarge)
+	var abuseErr *AbuseError
+	require.ErrorAs(t, err, &abuseErr)
+	assert.Equal(t, "line size", abuseErr.Metric)
+	assert.Greater(t, abuseErr.Value, float64(1024))
+	assert.Less(t, abuseErr.Value, float64(len(long)), "reading stopped before the end of the line")
+	assert.Len(t, evCh, 1)
+
+	// lines at the limit are fine
+	evCh = make(chan *Event, 1)
+	require.NoError(t, newSession("uri", Target{Chan: evCh}, &Options{MaxLineSize: len(long) - 2}).loop(context.Background(), strings.NewReader(long)))
+	assert.Len(t, evCh, 1)
+}
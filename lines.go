@@ -0,0 +1,150 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+//lineReader splits an event stream into lines, which may end in CRLF, LF or
+//CR alone.
+type lineReader struct {
+	br   *bufio.Reader
+	line []byte // the line being read, reused between calls
+	cr   bool   // whether the previous line ended in CR, so that an LF right after it belongs to it
+	max  int    // if positive, the longest line read before failing with errLineTooLong
+}
+
+//errLineTooLong is returned by readLine for a line longer than its max.
+var errLineTooLong = errors.New("line too long")
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{br: bufio.NewReader(r)}
+}
+
+//readLine returns the next line with its line ending replaced by a single LF.
+//At the end of the stream it returns the unterminated rest of the line, if
+//any, along with the error, and for a line exceeding max what has been read
+//of it, along with errLineTooLong. The returned slice is only valid until the
+//next call.
+func (r *lineReader) readLine() ([]byte, error) {
+	r.line = r.line[:0]
+	for {
+		// wait for data, then take all of it that is buffered
+		if _, err := r.br.Peek(1); err != nil {
+			return r.line, err
+		}
+		buf, _ := r.br.Peek(r.br.Buffered())
+
+		if r.cr {
+			r.cr = false
+			if buf[0] == '\n' {
+				r.br.Discard(1)
+				continue
+			}
+		}
+		if i := bytes.IndexAny(buf, "\r\n"); i >= 0 {
+			if r.max > 0 && len(r.line)+i > r.max {
+				r.line = append(r.line, buf[:i]...)
+				return r.line, errLineTooLong
+			}
+			r.line = append(append(r.line, buf[:i]...), '\n')
+			r.cr = buf[i] == '\r'
+			r.br.Discard(i + 1)
+			return r.line, nil
+		}
+		r.line = append(r.line, buf...)
+		r.br.Discard(len(buf))
+		if r.max > 0 && len(r.line) > r.max {
+			return r.line, errLineTooLong
+		}
+	}
+}
+
+//fieldReader reads the lines of an event stream and splits its fields into
+//name and value. It is the parser shared by Notify and Decoder, which each
+//act on the fields as they see fit.
+type fieldReader struct {
+	lr      *lineReader
+	started bool // whether the first line has been read
+}
+
+func newFieldReader(r io.Reader) *fieldReader {
+	return &fieldReader{lr: newLineReader(r)}
+}
+
+//field is a line of an event stream without its line ending: blank, a
+//comment starting with a colon, or a field split into name and value by
+//parseField.
+type field struct {
+	line      []byte
+	name, val []byte // empty for a blank line or a comment
+}
+
+//blank reports whether f is the blank line that ends an event.
+func (f field) blank() bool {
+	return len(f.line) == 0
+}
+
+//comment reports whether f is a comment.
+func (f field) comment() bool {
+	return len(f.line) != 0 && f.line[0] == ':'
+}
+
+//retry parses the value of a retry field, an unsigned number of
+//milliseconds.
+func (f field) retry() (time.Duration, error) {
+	i, err := strconv.ParseUint(string(f.val), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(i) * time.Millisecond, nil
+}
+
+//validID reports whether the value of an id field may be used, which the
+//spec forbids if it contains NUL.
+func (f field) validID() bool {
+	return bytes.IndexByte(f.val, 0) < 0
+}
+
+//next returns the next line of the stream, after stripping the byte order
+//mark from the first. On error, such as at the end of the stream, its line
+//holds what has been read of the unterminated line, see readLine. The
+//returned slices are only valid until the next call.
+func (r *fieldReader) next() (field, error) {
+	line, err := r.lr.readLine()
+	if !r.started {
+		line = bytes.TrimPrefix(line, bom)
+		r.started = true
+	}
+	if err != nil {
+		return field{line: line}, err
+	}
+	f := field{line: line[:len(line)-1]} // strip newline
+	if !f.blank() && !f.comment() {
+		f.name, f.val = parseField(f.line)
+	}
+	return f, nil
+}
+
+//bom is the UTF-8 byte order mark, which is stripped from the start of a
+//stream.
+var bom = []byte("\ufeff")
+
+//parseField splits line, without its terminator, into a field name and value.
+//If there is more than one delimiter, then the others are part of the value,
+//and a single space after the first is stripped.
+func parseField(line []byte) (name, val []byte) {
+	i := bytes.Index(line, delim)
+	if i < 0 {
+		return line, nil // the whole line is the name, the value is empty
+	}
+	name, val = line[:i], line[i+len(delim):]
+	if len(val) != 0 && val[0] == ' ' {
+		val = val[1:]
+	}
+	return name, val
+}
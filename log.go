@@ -0,0 +1,35 @@
+package sse
+
+import "io"
+
+//LogLevel selects which of a stream's messages are written to Logger. Each
+//level includes the levels above it.
+type LogLevel int
+
+const (
+	//LogDebug logs everything, down to the individual lines read. It is the
+	//default.
+	LogDebug LogLevel = iota
+	//LogInfo logs connections and input that was ignored.
+	LogInfo
+	//LogError logs only errors and dropped events.
+	LogError
+	//LogOff logs nothing.
+	LogOff
+)
+
+//logging reports whether messages of the given level are written, so that
+//hot paths can skip preparing them. Nothing is written while Logger discards
+//its output, as it does by default.
+func (s *session) logging(level LogLevel) bool {
+	return level >= s.opts.LogLevel && s.opts.LogLevel < LogOff && Logger.Writer() != io.Discard
+}
+
+//logf writes a message of the given level to Logger, unless Options.LogLevel
+//filters it out.
+func (s *session) logf(level LogLevel, format string, v ...interface{}) {
+	if !s.logging(level) {
+		return
+	}
+	Logger.Printf(format, v...)
+}
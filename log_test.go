@@ -0,0 +1,79 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevel(t *testing.T) {
+	const stream = ": comment\nretry: soon\ndata: event\n\ndata: cut"
+
+	tests := []struct {
+		level    LogLevel
+		logged   []string
+		unlogged []string
+	}{
+		{
+			level:  LogDebug,
+			logged: []string{"comment, ignoring", "received line of length", "received new event", "failed to parse retry field", "stream ended inside a line"},
+		},
+		{
+			level:    LogInfo,
+			logged:   []string{"failed to parse retry field", "stream ended inside a line"},
+			unlogged: []string{"comment, ignoring", "received line of length", "received new event"},
+		},
+		{
+			level:    LogOff,
+			unlogged: []string{"comment, ignoring", "received new event", "failed to parse retry field", "stream ended inside a line"},
+		},
+	}
+
+	for _, tt := range tests {
+		var logs bytes.Buffer
+		Logger.SetOutput(&logs)
+		evCh := make(chan *Event, 1)
+		require.NoError(t, newSession("", Target{Chan: evCh}, &Options{LogLevel: tt.level}).loop(context.Background(), strings.NewReader(stream)))
+		Logger.SetOutput(io.Discard)
+
+		for _, msg := range tt.logged {
+			assert.Contains(t, logs.String(), msg, "level %d", tt.level)
+		}
+		for _, msg := range tt.unlogged {
+			assert.NotContains(t, logs.String(), msg, "level %d", tt.level)
+		}
+	}
+}
+
+func TestLoggingDiscarded(t *testing.T) {
+	s := newSession("", Target{}, nil)
+	assert.False(t, s.logging(LogError), "Logger discards by default")
+
+	Logger.SetOutput(&bytes.Buffer{})
+	defer Logger.SetOutput(io.Discard)
+	assert.True(t, s.logging(LogDebug))
+	s.opts.LogLevel = LogError
+	assert.False(t, s.logging(LogInfo))
+	assert.True(t, s.logging(LogError))
+}
+
+func TestLoopDebugLoggingAllocs(t *testing.T) {
+	line := "data: " + strings.Repeat("x", 1000) + "\n"
+	stream := strings.Repeat(line, 100) + "\n"
+	evCh := make(chan *Event, 1)
+
+	allocs := func(level LogLevel) float64 {
+		Logger.SetOutput(&bytes.Buffer{})
+		defer Logger.SetOutput(io.Discard)
+		return testing.AllocsPerRun(10, func() {
+			assert.NoError(t, newSession("", Target{Chan: evCh}, &Options{LogLevel: level}).loop(context.Background(), strings.NewReader(stream)))
+			<-evCh
+		})
+	}
+	assert.Less(t, allocs(LogError)+100, allocs(LogDebug), "lines are not formatted above LogDebug")
+}
@@ -0,0 +1,380 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+//Options configures a single stream opened by NotifyWithOptions. The zero value
+//gives the same behaviour as Notify.
+type Options struct {
+	//EmitMetadataOnlyBlocks makes a block consisting only of id: and/or retry:
+	//fields dispatch an Event with an empty Type and Data. By default such a
+	//block only updates the last event ID and reconnection time.
+	EmitMetadataOnlyBlocks bool
+
+	//MaxEventsPerSecond, if positive, aborts the connection with an
+	//*AbuseError once more than this many events arrive within one second.
+	MaxEventsPerSecond int
+
+	//MaxAverageEventSize, if positive, aborts the connection with an
+	//*AbuseError once the average Data length of the events received on it
+	//exceeds this many bytes.
+	MaxAverageEventSize int
+
+	//MaxEventBytes, if positive, limits the size of the data of a single
+	//event, which is otherwise buffered without bound. OnOversize decides what
+	//happens to an event exceeding it. It does not apply with StreamData.
+	MaxEventBytes int
+	OnOversize    OversizePolicy
+
+	//MaxLineSize, if positive, aborts the connection with an *AbuseError
+	//matching ErrEventTooLarge once a single line exceeds this many bytes,
+	//before the rest of it is read, so that a server cannot exhaust memory
+	//with an endless line. Unlike MaxEventBytes it also applies with
+	//StreamData.
+	MaxLineSize int
+
+	//PreserveAcceptHeader keeps an Accept header set by GetReq instead of
+	//overwriting it with "text/event-stream". The Content-Type of the response
+	//is validated regardless.
+	PreserveAcceptHeader bool
+
+	//ResumeQueryParam, if set, names a query parameter that is set to the
+	//last event ID on reconnect requests, for servers that resume from e.g.
+	//"?since=<id>" rather than from the Last-Event-ID header. The header is
+	//sent as well, unless ResumeIgnoredHeader is set.
+	ResumeQueryParam string
+
+	//ResumeIgnoredHeader, if set together with ResumeQueryParam, names a
+	//response header by which a server signals that it ignores the
+	//Last-Event-ID header. Until a response carries it with a non-empty value,
+	//reconnects resume through the header only; from then on they resume
+	//through ResumeQueryParam only. This suits pools mixing both kinds of
+	//server. Without ResumeQueryParam it has no effect.
+	ResumeIgnoredHeader string
+
+	//IDUpdatePredicate, if set, decides for each dispatched event whether its
+	//ID becomes the last event ID used to resume the stream. Events for which
+	//it returns false are still delivered, but a reconnect resumes from the
+	//last event it accepted, which keeps ephemeral events on an interleaved
+	//stream from moving the cursor.
+	IDUpdatePredicate func(ev *Event) bool
+
+	//ShouldReconnect, if set, is consulted when retry is enabled and a
+	//connection attempt fails, either with a *TransportError or because the
+	//server's response was rejected. Returning true waits for the current
+	//reconnection time and tries again instead of returning err.
+	ShouldReconnect func(err error) bool
+
+	//RetryOnStatus, if set, decides whether a response with the given status
+	//other than 200 is retried like ShouldReconnect does, e.g. for the
+	//transient 502 and 503 of a restarting server. By default any such status
+	//is fatal.
+	RetryOnStatus func(code int) bool
+
+	//PreConnect, if set, is run before each connection attempt, including
+	//reconnects, e.g. to fetch a fresh short-lived ticket that InjectHeaders
+	//or GetReq then adds to the request. If it returns an error the attempt
+	//fails with that error, which ShouldReconnect may choose to retry.
+	PreConnect func(ctx context.Context) error
+
+	//NoReplay never asks the server to resume from the last event ID, neither
+	//through the Last-Event-ID header nor ResumeQueryParam, so that every
+	//connection starts from "now". Events missed while reconnecting are lost.
+	NoReplay bool
+
+	//LastEventID, if set, is sent as the Last-Event-ID of the first request,
+	//to resume a stream where a previous run, e.g. of NotifyResumable, left
+	//off.
+	LastEventID string
+
+	//OnGap, if set, is called after each reconnect with the time that passed
+	//between losing the previous connection and receiving the first event on
+	//the new one, i.e. the window in which events may have been missed.
+	OnGap func(d time.Duration)
+
+	//DefaultEventType, if set, becomes the Type of events that have no event:
+	//field, e.g. MessageEventType to match the browser EventSource. As the
+	//type is reset after each event, this includes events following a
+	//typed one. It is applied as soon as the event is dispatched, so
+	//everything that looks at the event afterwards, such as
+	//IDUpdatePredicate or a consumer routing events by Type, sees the
+	//defaulted type.
+	DefaultEventType string
+
+	//NormalizeType, if set, maps the type of each event to its canonical form,
+	//e.g. strings.ToLower after strings.TrimSpace for servers that are sloppy
+	//about case and whitespace. The type as sent is kept in Event.RawType.
+	//DefaultEventType applies if the result is empty.
+	NormalizeType func(string) string
+
+	//IDOrder, if not IDOrderNone, checks that each event carrying an id:
+	//field has a greater ID than the previous one, and calls OnIDRegression
+	//if it does not. Events are delivered either way.
+	IDOrder IDOrder
+
+	//DedupWindow, if positive, remembers the IDs of the last this many events
+	//that carried an id: field and drops an event whose ID is among them,
+	//such as those some servers replay when resuming from Last-Event-ID.
+	//The IDs are remembered for the whole stream, across reconnects. Events
+	//without an id: field of their own are always delivered.
+	DedupWindow int
+
+	//OnIDRegression is called with the previous and current ID when IDOrder
+	//finds an ID that does not increase.
+	OnIDRegression func(prev, id string)
+
+	//ValidateUTF8 replaces invalid UTF-8 sequences in each event's Data with
+	//U+FFFD, as the stream is defined to be UTF-8, logging an error for each
+	//event that had any. Without it Data is passed through as received.
+	ValidateUTF8 bool
+
+	//Client, if set, is used for the stream's requests, including
+	//reconnects, instead of the package's Client, e.g. for a provider that
+	//needs its own proxy or TLS configuration. The same caveat about its
+	//Timeout applies.
+	Client *http.Client
+
+	//Header holds static headers, such as Authorization or X-API-Key, to set
+	//on every request, including reconnects, without replacing GetReq. They
+	//replace headers of the same name set by GetReq, but never Accept or
+	//the headers set for resuming.
+	Header http.Header
+
+	//InjectHeaders, if set, is called with the stream's context for every
+	//request, including reconnects, to add headers derived from it. This is
+	//where a tracing propagator injects W3C trace context headers such as
+	//traceparent, letting the server correlate the stream with the trace.
+	InjectHeaders func(ctx context.Context, h http.Header)
+
+	//ConnectTimeout, if positive, fails a connection attempt with
+	//ErrConnectTimeout when its response headers do not arrive within this
+	//time, covering DNS, dialing and TLS too. Unlike Client.Timeout it does
+	//not limit the stream once it is open. Whether the attempt is then
+	//retried is up to ShouldReconnect.
+	ConnectTimeout time.Duration
+
+	//FirstEventTimeout, if positive, drops a connection with
+	//ErrFirstEventTimeout when no event is dispatched on it within this time
+	//of connecting, reconnecting if retry is enabled. Unlike a read timeout it
+	//is not satisfied by comments or other lines, catching servers that keep
+	//the connection alive without ever sending data.
+	FirstEventTimeout time.Duration
+
+	//ReadTimeout, if positive, drops a connection with ErrReadTimeout when no
+	//line, including comments, arrives on it for this long, reconnecting if
+	//retry is enabled. This detects connections that were lost without being
+	//closed; servers should then send keep-alive comments more often.
+	//Since comments count, it also serves as a heartbeat liveness timeout.
+	//Time spent waiting for the Target to take an event does not count.
+	ReadTimeout time.Duration
+
+	//OnStale, if set, is called from its own goroutine when ReadTimeout
+	//drops a silent connection, just before it is dropped.
+	OnStale func()
+
+	//MaxRuntime, if positive, stops the stream cleanly once it has run this
+	//long in total, across reconnects, returning a nil error. Together with
+	//NotifyResumable, whose resume position can be passed to the next run,
+	//this suits batch consumers working in bounded windows.
+	MaxRuntime time.Duration
+
+	//Checksum, if set, verifies the data of each event that carries a
+	//checksum field against it. Events without the field are delivered
+	//unverified.
+	Checksum *Checksum
+
+	//Base64, if set, decodes the base64 encoded data of events before they
+	//are delivered, after verifying their Checksum. See Base64.
+	Base64 *Base64
+
+	//LengthField, if set, names a field in which the server announces the
+	//length in bytes of each event's data, lines joined by "\n". An event
+	//whose data has a different length aborts the connection with a
+	//*LengthError. Events without the field are not checked.
+	LengthField string
+
+	//RawTap, if set, receives a copy of the raw bytes of each connection as
+	//they are read, before parsing, e.g. to record the stream for replay
+	//with NotifyFile. Errors writing to it are logged and otherwise ignored.
+	RawTap io.Writer
+
+	//MaxEmptyReads, if positive, treats the connection as broken, failing it
+	//with io.ErrNoProgress, once reading its body returns no data and no
+	//error this many times in a row. Otherwise bufio gives up after 100.
+	MaxEmptyReads int
+
+	//FlushOnEOF dispatches an event left incomplete when the stream ends
+	//cleanly without the blank line terminating it, including its final
+	//line if the stream ended inside it. By default such an event is
+	//discarded, as the specification requires.
+	FlushOnEOF bool
+
+	//Strict aborts the connection with a *MalformedLineError on lines that
+	//have no colon and are not a known field name, such as stray plain text.
+	//By default they are ignored without affecting the events around them.
+	Strict bool
+
+	//Coordinator, if set, staggers reconnects with the other streams sharing
+	//it. See ReconnectCoordinator.
+	Coordinator *ReconnectCoordinator
+
+	//OnConnect, if set, is called with the response of each successful
+	//connection, including reconnects, once its status and Content-Type have
+	//been checked and before its events are read, e.g. to log a request ID
+	//header. Use ParseServerTiming to extract the server's latency metrics
+	//from it.
+	OnConnect func(res *http.Response)
+
+	//OnTrailer, if set, is called with the trailers of a response whose
+	//stream ended cleanly, if it sent any, e.g. final metadata sent by an
+	//HTTP/2 server. It is not called for connections that were dropped.
+	OnTrailer func(trailer http.Header)
+
+	//OnReconnect, if set, is called before each reconnect attempt with the
+	//last event ID the request resumes from, "" if none is sent, e.g. to
+	//assert that the resume cursor is what the application expects.
+	//Following NextField to the next URI is not a reconnect.
+	OnReconnect func(lastEventID string)
+
+	//ProxyCredentials, if set, are added to the proxy URLs returned by the
+	//Proxy of the client's *http.Transport, unless they carry credentials of
+	//their own, for forward proxies that require basic authentication. The
+	//transport then sends them as Proxy-Authorization to the proxy alone,
+	//both for plain http streams, which the proxy forwards, and for https
+	//streams tunnelled through CONNECT. Requests that bypass the proxy, e.g.
+	//through NO_PROXY, never carry them. Clients with another RoundTripper
+	//are used as they are; put the credentials in their proxy URL instead.
+	ProxyCredentials *url.Userinfo
+
+	//LogLevel limits the messages about this stream written to Logger, see
+	//LogLevel. The zero value logs everything.
+	LogLevel LogLevel
+
+	//OnStats, if set, is called with the ParseStats of each connection when
+	//it ends, for monitoring the quality of the server's stream.
+	OnStats func(ParseStats)
+
+	//CommentMode selects how comment lines, typically keep-alives, are
+	//treated; by default they are ignored. ParseStats counts them either way.
+	CommentMode CommentMode
+	//OnComment, if set, is called with the text of each comment, after the
+	//colon and a single space, whatever the CommentMode; e.g. to observe
+	//": ping" heartbeats.
+	OnComment func(text string)
+
+	//TickInterval, if positive, sends a synthetic event of type TickEventType
+	//down the channel at this interval for as long as the stream runs,
+	//including while reconnecting, so that a single select loop can also
+	//drive periodic work. Ticks are not sent to a Target's Func.
+	TickInterval time.Duration
+
+	//NextField, if set, names a field by which the server sends the URI of
+	//the next segment of a paginated stream, possibly relative to the current
+	//one. When the connection then ends cleanly, the stream continues there
+	//immediately, without waiting for the reconnection time and even if retry
+	//is disabled. Later reconnects use the new URI.
+	NextField string
+
+	//StreamData, if set, receives the data of events line by line as it
+	//arrives instead of it being collected in Event.Data, so that huge events
+	//need not be held in memory. The events are still delivered, without
+	//Data.
+	StreamData *DataStreamer
+
+	//Metrics, if set, is notified of the stream's activity, e.g. to update
+	//counters of an external metrics system.
+	Metrics *Metrics
+
+	//Sequence sets Event.Seq and Event.ConnIndex, which makes it easy to see
+	//where reconnects happened in a log of events.
+	Sequence bool
+
+	//Jitter randomizes each wait before reconnecting by up to this fraction
+	//of the reconnection time in either direction, e.g. 0.1 for ±10%, so
+	//that clients dropped at the same time do not all reconnect at once.
+	//Valid values range from 0 to 1; greater ones are treated as 1.
+	Jitter float64
+
+	//BackoffFactor multiplies the reconnection time after each consecutive
+	//failed connection attempt, e.g. 2 to double it every time, so that a
+	//server that is down is not hammered. The reconnection time set by the
+	//server's retry: field is the base, and a successful connection resets
+	//the backoff. Values of 1 or less disable it.
+	BackoffFactor float64
+
+	//MaxBackoff caps the reconnection time grown by BackoffFactor. Zero means
+	//no cap.
+	MaxBackoff time.Duration
+
+	//MinReconnectInterval, if set, is the least time waited before any
+	//reconnect, overriding a smaller reconnection time requested by the server
+	//through a retry: field, so that a misconfigured server cannot cause a
+	//tight reconnect loop.
+	MinReconnectInterval time.Duration
+
+	//OnRetryChange, if set, is called with the new reconnection time each time
+	//the server sends a valid retry: field. Malformed ones are ignored.
+	OnRetryChange func(d time.Duration)
+
+	//ResetRetry reverts the reconnection time to the default of one second on
+	//each new connection, so that a retry: field only applies to reconnects
+	//after the connection that sent it. By default the last value the server
+	//sent is kept across connections, even those that do not send one.
+	ResetRetry bool
+
+	//Rand is the source of randomness for Jitter. It defaults to the
+	//top-level functions of math/rand; set it to a seeded *rand.Rand to make
+	//the waits deterministic, e.g. in tests. A *rand.Rand is not safe for
+	//concurrent use, so it must not be shared between streams.
+	Rand *rand.Rand
+}
+
+//DataStreamer receives the data of events as it arrives, see
+//Options.StreamData. All of its functions are optional and are called
+//synchronously while reading the stream.
+type DataStreamer struct {
+	//Start is called when the first field of an event arrives.
+	Start func()
+
+	//Line is called with the value of each data: field of the event. It is
+	//only valid during the call.
+	Line func(line []byte)
+
+	//End is called with the complete event, just before it is delivered.
+	End func(ev *Event)
+}
+
+//Metrics receives notifications of a stream's activity, see Options.Metrics.
+//All of its functions are optional and are called synchronously.
+type Metrics struct {
+	//Event is called with each event just before it is delivered.
+	Event func(ev *Event)
+
+	//Bytes is called with the length of each line read, including its
+	//terminator, which counts as one byte even if it was a CRLF.
+	Bytes func(n int)
+
+	//Comment is called for each comment line read, whatever the
+	//Options.CommentMode, e.g. to count keep-alive comments.
+	Comment func()
+
+	//Reconnect is called before each reconnect attempt with its number,
+	//starting at 1 and counting up across the attempts of the stream, and
+	//the error that ended the previous connection or attempt, nil if the
+	//stream ended cleanly. Following Options.NextField is not counted.
+	Reconnect func(attempt int, cause error)
+}
+
+//client returns the client to use for requests.
+func (o *Options) client() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return Client
+}
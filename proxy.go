@@ -0,0 +1,35 @@
+package sse
+
+import (
+	"net/http"
+	"net/url"
+)
+
+//withProxyCredentials returns a copy of c whose transport adds user to the
+//proxy URLs it uses, see Options.ProxyCredentials. c is returned as is if its
+//transport is not an *http.Transport or uses no proxy.
+func withProxyCredentials(c *http.Client, user *url.Userinfo) *http.Client {
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok || t.Proxy == nil {
+		return c
+	}
+
+	t = t.Clone()
+	proxy := t.Proxy
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		u, err := proxy(req)
+		if err != nil || u == nil || u.User != nil {
+			return u, err
+		}
+		withUser := *u
+		withUser.User = user
+		return &withUser, nil
+	}
+	proxied := *c
+	proxied.Transport = t
+	return &proxied
+}
@@ -0,0 +1,84 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyCredentials(t *testing.T) {
+	var originAuths, proxyAuths []string
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originAuths = append(originAuths, r.Header.Get("Proxy-Authorization"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer origin.Close()
+
+	// forwards requests to the origin, passing on all headers but its own
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuths = append(proxyAuths, r.Header.Get("Proxy-Authorization"))
+		if r.Header.Get("Proxy-Authorization") == "" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		req, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), nil)
+		require.NoError(t, err)
+		req.Header = r.Header.Clone()
+		req.Header.Del("Proxy-Authorization")
+		res, err := http.DefaultTransport.RoundTrip(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		for k, v := range res.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(res.StatusCode)
+		_, err = io.Copy(w, res.Body)
+		assert.NoError(t, err)
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	transport := &http.Transport{Proxy: func(req *http.Request) (*url.URL, error) {
+		if req.URL.Path == "/direct" {
+			return nil, nil // bypasses the proxy, as with NO_PROXY
+		}
+		return proxyURL, nil
+	}}
+	opts := &Options{
+		Client:           &http.Client{Transport: transport},
+		ProxyCredentials: url.UserPassword("user", "secret"),
+	}
+
+	for _, path := range []string{"/proxied", "/direct"} {
+		evCh := make(chan *Event, 1)
+		require.NoError(t, NotifyWithOptions(context.Background(), origin.URL+path, false, evCh, opts))
+		assert.Len(t, evCh, 1, path)
+	}
+	assert.Equal(t, []string{"Basic dXNlcjpzZWNyZXQ="}, proxyAuths)
+	assert.Equal(t, []string{"", ""}, originAuths, "the origin never sees the credentials")
+
+	// the client's own transport is left alone
+	u, err := transport.Proxy(httptest.NewRequest(http.MethodGet, origin.URL, nil))
+	require.NoError(t, err)
+	assert.Nil(t, u.User)
+}
+
+func TestProxyCredentialsKeepURLUser(t *testing.T) {
+	own := url.UserPassword("own", "pass")
+	c := withProxyCredentials(&http.Client{Transport: &http.Transport{
+		Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: "proxy.invalid", User: own}),
+	}}, url.UserPassword("user", "secret"))
+
+	u, err := c.Transport.(*http.Transport).Proxy(httptest.NewRequest(http.MethodGet, "http://stream.invalid/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, own, u.User)
+}
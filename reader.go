@@ -0,0 +1,31 @@
+package sse
+
+import (
+	"io"
+	"time"
+)
+
+//Reader reads events one at a time from an event stream held by any
+//io.Reader, such as a file, a pipe or a websocket bridge. It is the parser
+//behind Decoder under the Read/NewReader names of the io family; use Decoder
+//directly to decode into a reused Event.
+type Reader struct {
+	dec *Decoder
+}
+
+//NewReader returns a Reader reading from r. If r is nil, reading fails with
+//ErrNilReader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: NewDecoder(r)}
+}
+
+//Read returns the next event of the stream, or io.EOF at its end.
+func (r *Reader) Read() (*Event, error) {
+	return r.dec.Decode()
+}
+
+//Retry returns the reconnection time most recently sent by the stream through
+//a retry: field, or 0 if it has not sent one.
+func (r *Reader) Retry() time.Duration {
+	return r.dec.Retry()
+}
@@ -0,0 +1,27 @@
+package sse
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader("retry: 500\nid: 1\nevent: a\ndata: first\n\n: comment\ndata: second\n\ndata: unterminated"))
+
+	ev, err := r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, &Event{ID: "1", Type: "a", Data: []byte("first")}, ev)
+	assert.Equal(t, 500*time.Millisecond, r.Retry())
+
+	ev, err = r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, &Event{ID: "1", Data: []byte("second")}, ev)
+
+	_, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,106 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+//pause waits before reconnecting, for wait and then for the turn of the
+//stream with Options.Coordinator, if set. If ctx is done first it returns the
+//cause.
+func (s *session) pause(ctx context.Context, wait time.Duration) error {
+	t := time.NewTimer(wait)
+	select {
+	case <-ctx.Done():
+		t.Stop()
+		return context.Cause(ctx)
+	case <-t.C:
+	}
+	if c := s.opts.Coordinator; c != nil {
+		var host string
+		if u, err := url.Parse(s.uri); err == nil {
+			host = u.Host
+		}
+		c.wait(ctx, host)
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return nil
+}
+
+//shouldReconnect reports whether the failed connection attempt err is retried,
+//according to Options.RetryOnStatus and Options.ShouldReconnect.
+func (s *session) shouldReconnect(err error) bool {
+	var statusErr *StatusError
+	if s.opts.RetryOnStatus != nil && errors.As(err, &statusErr) && s.opts.RetryOnStatus(statusErr.StatusCode) {
+		return true
+	}
+	return s.opts.ShouldReconnect != nil && s.opts.ShouldReconnect(err)
+}
+
+//nextWait returns the time to wait before the next reconnect, consuming a
+//wait requested through Retry-After.
+func (s *session) nextWait() time.Duration {
+	wait := s.reconnectWait()
+	s.retryAfter = 0
+	return wait
+}
+
+//reconnectWait returns the time to wait before the next reconnect: the
+//current reconnection time, grown by Options.BackoffFactor for each
+//consecutive failure up to Options.MaxBackoff, randomized according to
+//Options.Jitter, but no less than Options.MinReconnectInterval. A wait
+//requested through Retry-After is used as is instead.
+func (s *session) reconnectWait() time.Duration {
+	if s.retryAfter > 0 {
+		return max(s.retryAfter, s.opts.MinReconnectInterval)
+	}
+	wait := s.wait
+	if b := s.opts.BackoffFactor; b > 1 {
+		for i := 0; i < s.failures; i++ {
+			next := float64(wait) * b
+			if next >= math.MaxInt64 {
+				break
+			}
+			wait = time.Duration(next)
+			if s.opts.MaxBackoff > 0 && wait >= s.opts.MaxBackoff {
+				wait = s.opts.MaxBackoff
+				break
+			}
+		}
+	}
+	if j := min(s.opts.Jitter, 1); j > 0 { // more than ±100% could make the wait negative
+		f := rand.Float64
+		if s.opts.Rand != nil {
+			f = s.opts.Rand.Float64
+		}
+		wait = time.Duration(float64(wait) * (1 + j*(2*f()-1)))
+	}
+	if wait < s.opts.MinReconnectInterval {
+		wait = s.opts.MinReconnectInterval
+	}
+	return wait
+}
+
+//parseRetryAfter returns the wait requested by the Retry-After header value h,
+//given either in seconds or as an HTTP date relative to now, or 0 if h is
+//empty, invalid or in the past.
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseUint(h, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
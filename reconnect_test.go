@@ -0,0 +1,286 @@
+package sse
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconnectWaitJitter(t *testing.T) {
+	waits := func(seed int64) []time.Duration {
+		s := newSession("", Target{}, &Options{Jitter: 0.5, Rand: rand.New(rand.NewSource(seed))})
+		s.wait = time.Second
+
+		var waits []time.Duration
+		for i := 0; i < 5; i++ {
+			waits = append(waits, s.reconnectWait())
+		}
+		return waits
+	}
+
+	first := waits(42)
+	assert.Equal(t, first, waits(42))
+	assert.NotEqual(t, first, waits(43))
+	for _, wait := range first {
+		assert.GreaterOrEqual(t, wait, 500*time.Millisecond)
+		assert.LessOrEqual(t, wait, 1500*time.Millisecond)
+	}
+	assert.NotEqual(t, first[0], first[1])
+}
+
+func TestReconnectWaitJitterClamped(t *testing.T) {
+	s := newSession("", Target{}, &Options{Jitter: 5, Rand: rand.New(rand.NewSource(42))})
+	s.wait = time.Second
+	for i := 0; i < 100; i++ {
+		wait := s.reconnectWait()
+		assert.GreaterOrEqual(t, wait, time.Duration(0))
+		assert.LessOrEqual(t, wait, 2*time.Second)
+	}
+}
+
+func TestReconnectWaitNoJitter(t *testing.T) {
+	s := newSession("", Target{}, nil)
+	assert.Equal(t, defaultWait, s.reconnectWait())
+}
+
+func TestMinReconnectInterval(t *testing.T) {
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+		if len(requests) > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 1\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	evCh := make(chan *Event, 1)
+	opts := &Options{MinReconnectInterval: 100 * time.Millisecond}
+	assert.Error(t, NotifyWithOptions(context.Background(), server.URL, true, evCh, opts))
+	require.Len(t, requests, 2)
+	assert.GreaterOrEqual(t, requests[1].Sub(requests[0]), 100*time.Millisecond)
+}
+
+func TestRetryOnStatus(t *testing.T) {
+	var statuses []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch len(statuses) {
+		case 0:
+			statuses = append(statuses, 503)
+			w.WriteHeader(503)
+		case 1:
+			statuses = append(statuses, 200)
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("data: event\n\n"))
+			assert.NoError(t, err)
+		default:
+			statuses = append(statuses, 404)
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	var (
+		evCh = make(chan *Event, 1)
+		opts = &Options{
+			RetryOnStatus:        func(code int) bool { return code >= 500 },
+			MinReconnectInterval: 10 * time.Millisecond,
+		}
+	)
+	// reconnect after MinReconnectInterval rather than the default wait
+	sess := newSession(server.URL, Target{Chan: evCh}, opts)
+	sess.wait = 0
+	_, _, err := notify(context.Background(), true, sess)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 404, statusErr.StatusCode)
+	assert.Equal(t, []int{503, 200, 404}, statuses)
+	assert.Len(t, evCh, 1)
+
+	statuses = nil
+	err = NotifyWithOptions(context.Background(), server.URL, true, evCh, nil)
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 503, statusErr.StatusCode, "statuses are fatal by default")
+}
+
+func TestCancelDuringReconnectWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 60000\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		evCh        = make(chan *Event)
+		done        = make(chan error)
+	)
+	defer cancel()
+	go func() { done <- Notify(ctx, server.URL, true, evCh) }()
+
+	<-evCh // the connection then ends, so Notify waits a minute to reconnect
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Notify did not return after cancellation")
+	}
+}
+
+func TestReconnectWaitBackoff(t *testing.T) {
+	s := newSession("", Target{}, &Options{BackoffFactor: 2, MaxBackoff: 5 * time.Second})
+	s.wait = time.Second
+
+	var waits []time.Duration
+	for s.failures = 0; s.failures < 5; s.failures++ {
+		waits = append(waits, s.reconnectWait())
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, waits)
+
+	s.opts.MaxBackoff = 0
+	s.failures = 1000
+	assert.Greater(t, s.reconnectWait(), 1000*time.Hour)
+}
+
+func TestBackoffAcrossFailures(t *testing.T) {
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+		switch len(requests) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 20\ndata: event\n\n"))
+			assert.NoError(t, err)
+		case 2, 3, 4:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	evCh := make(chan *Event, 1)
+	opts := &Options{
+		BackoffFactor: 2,
+		RetryOnStatus: func(code int) bool { return code == http.StatusServiceUnavailable },
+	}
+	assert.Error(t, NotifyWithOptions(context.Background(), server.URL, true, evCh, opts))
+	require.Len(t, requests, 5)
+
+	//Each failure doubles the wait: 20ms after the stream ends, then 20, 40
+	//and 80ms after the failed attempts.
+	var gaps []time.Duration
+	for i := 1; i < len(requests); i++ {
+		gaps = append(gaps, requests[i].Sub(requests[i-1]))
+	}
+	assert.GreaterOrEqual(t, gaps[0], 20*time.Millisecond)
+	assert.GreaterOrEqual(t, gaps[1], 20*time.Millisecond)
+	assert.GreaterOrEqual(t, gaps[2], 40*time.Millisecond)
+	assert.GreaterOrEqual(t, gaps[3], 80*time.Millisecond)
+	assert.Greater(t, gaps[3], gaps[1])
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(90 * time.Second).Format(time.RFC850), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseRetryAfter(tt.header, now), tt.header)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+		if len(requests) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	opts := &Options{RetryOnStatus: func(code int) bool { return code == http.StatusTooManyRequests }}
+	sess := newSession(server.URL, Target{Chan: make(chan *Event)}, opts)
+	sess.wait = time.Minute // overridden by Retry-After
+	_, wait, err := notify(context.Background(), true, sess)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	require.Len(t, requests, 2)
+	assert.GreaterOrEqual(t, requests[1].Sub(requests[0]), time.Second)
+	assert.Less(t, requests[1].Sub(requests[0]), 5*time.Second)
+	assert.Equal(t, time.Minute, wait, "the reconnection time is kept")
+	assert.Zero(t, sess.retryAfter, "Retry-After applies to one attempt only")
+}
+
+func TestResetRetry(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		reset bool
+		want  time.Duration
+	}{
+		{"sticky", false, 20 * time.Millisecond},
+		{"reset", true, defaultWait},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var n int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n++
+				w.Header().Set("Content-Type", "text/event-stream")
+				if n == 1 {
+					_, err := w.Write([]byte("retry: 20\ndata: event 1\n\n"))
+					assert.NoError(t, err)
+					return
+				}
+				_, err := w.Write([]byte("data: event 2\n\n"))
+				assert.NoError(t, err)
+			}))
+			defer server.Close()
+
+			var (
+				waits []time.Duration
+				sess  = newSession(server.URL, Target{}, &Options{ResetRetry: tt.reset})
+			)
+			sess.t.Func = func(*Event) error {
+				waits = append(waits, sess.wait)
+				if len(waits) == 2 {
+					return errStopIteration
+				}
+				return nil
+			}
+			_, _, err := notify(context.Background(), true, sess)
+			assert.Equal(t, errStopIteration, err)
+			assert.Equal(t, []time.Duration{20 * time.Millisecond, tt.want}, waits)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package sse
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+//Relay decodes the event stream read from upstream and re-encodes it to
+//downstream event by event, for gateways that front an upstream server. If
+//rewrite is non-nil it is called with each event before it is written, e.g.
+//to map the upstream IDs to the gateway's own, and with an Event holding just
+//the ID for a block that only sets the last event ID, which is relayed as
+//such. Events without a type or data are relayed as events too, and an
+//upstream reset of the last event ID to "" through an empty id: field is
+//passed on. Changes of the reconnection time are passed on as well, even
+//after the last event. If downstream is an http.Flusher it is flushed after
+//each block. Relay returns nil at the end of upstream.
+func Relay(upstream io.Reader, downstream io.Writer, rewrite func(*Event)) error {
+	var (
+		dec       = NewDecoder(upstream)
+		f, _      = downstream.(http.Flusher)
+		relayed   = dec.Retry()
+		relayedID string // last event ID as written downstream
+	)
+	relayRetry := func() error {
+		wait := dec.Retry()
+		if wait == relayed {
+			return nil
+		}
+		relayed = wait
+		_, err := fmt.Fprintf(downstream, "%s: %d\n", rName, wait.Milliseconds())
+		return err
+	}
+	for {
+		ev := &Event{}
+		event, err := dec.decode(ev, true)
+		if err == io.EOF {
+			if err := relayRetry(); err != nil {
+				return err
+			}
+			if f != nil {
+				f.Flush()
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := relayRetry(); err != nil {
+			return err
+		}
+		if rewrite != nil {
+			rewrite(ev)
+		}
+		if ev.ID == "" && relayedID != "" {
+			// writeEvent leaves out an empty ID, which would keep the old one
+			if _, err := fmt.Fprintf(downstream, "%s:\n", iName); err != nil {
+				return err
+			}
+		}
+		if err := writeEvent(downstream, ev, event); err != nil {
+			return err
+		}
+		relayedID = ev.ID
+		if f != nil {
+			f.Flush()
+		}
+	}
+}
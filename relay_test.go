@@ -0,0 +1,93 @@
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelay(t *testing.T) {
+	var (
+		upstream = ": comment\nretry: 500\nid: u-1\nevent: update\ndata: a\ndata: b\n\nid: u-2\ndata: c\n\ndata: unterminated"
+		out      bytes.Buffer
+	)
+	err := Relay(strings.NewReader(upstream), &out, func(ev *Event) {
+		ev.ID = strings.Replace(ev.ID, "u-", "g-", 1)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "retry: 500\nid: g-1\nevent: update\ndata: a\ndata: b\n\nid: g-2\ndata: c\n\n", out.String())
+}
+
+func TestRelayRoundTrip(t *testing.T) {
+	decodeAll := func(stream string) ([]*Event, time.Duration) {
+		dec := NewDecoder(strings.NewReader(stream))
+		var events []*Event
+		for {
+			ev, err := dec.Decode()
+			if err != nil {
+				require.Equal(t, io.EOF, err)
+				return events, dec.Retry()
+			}
+			events = append(events, ev)
+		}
+	}
+
+	for name, upstream := range map[string]string{
+		"empty event":    "data\n\nid: 1\ndata\n\ndata: a\n\n",
+		"trailing retry": "data: a\n\nretry: 250\n",
+		"id reset":       "id: 1\ndata: a\n\nid\ndata: b\n\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, Relay(strings.NewReader(upstream), &out, nil))
+
+			want, wantRetry := decodeAll(upstream)
+			got, gotRetry := decodeAll(out.String())
+			assert.Equal(t, want, got)
+			assert.Equal(t, wantRetry, gotRetry)
+		})
+	}
+}
+
+func TestRelayIDs(t *testing.T) {
+	tests := []struct {
+		name, upstream, want string
+	}{
+		{"reset", "id: 1\ndata: a\n\nid\ndata: b\n\n", "id: 1\ndata: a\n\nid:\ndata: b\n\n"},
+		{"id-only block", "data: a\n\nid: 7\n\ndata: b\n\n", "data: a\n\nid: 7\n\nid: 7\ndata: b\n\n"},
+		{"trailing id-only block", "id: 1\ndata: a\n\nid: 2\n\n", "id: 1\ndata: a\n\nid: 2\n\n"},
+		{"id-only reset", "id: 1\ndata: a\n\nid:\n\n", "id: 1\ndata: a\n\nid:\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, Relay(strings.NewReader(tt.upstream), &out, nil))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	t.Run("rewritten", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, Relay(strings.NewReader("data: a\n\nid: u-7\n\n"), &out, func(ev *Event) {
+			ev.ID = strings.Replace(ev.ID, "u-", "g-", 1)
+		}))
+		assert.Equal(t, "data: a\n\nid: g-7\n\n", out.String())
+	})
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestRelayWriteError(t *testing.T) {
+	err := Relay(strings.NewReader("data: a\n\n"), errWriter{}, nil)
+	assert.EqualError(t, err, "closed")
+}
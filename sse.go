@@ -1,16 +1,16 @@
 package sse
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net/http"
-	"strconv"
-	"strings"
+	"net/url"
 	"time"
 )
 
@@ -25,44 +25,182 @@ const (
 )
 
 var (
-	//ErrNilChan will be returned by Notify if it is passed a nil channel
-	ErrNilChan = fmt.Errorf("nil channel given")
+	//errMaxRuntime ends a stream that reaches Options.MaxRuntime.
+	errMaxRuntime = fmt.Errorf("maximum runtime reached")
 
-	//Client is the default client used for requests.
+	//Client is the default client used for requests. It must not have a
+	//Timeout: that bounds the whole exchange, including reading the body, so
+	//it would cut every stream off after the timeout. Bound the time taken to
+	//connect with the transport's settings or a context deadline instead.
 	Client = &http.Client{}
 
 	//Logger is used to log debug messages. By default logging is disabled;
-	//to enable, use SetOutput() or overwrite this instance.
+	//to enable, use SetOutput() or overwrite this instance. Options.LogLevel
+	//selects the messages logged for each stream.
 	Logger = log.New(ioutil.Discard, "", log.LstdFlags)
 
 	delim = []byte{':'}
 )
 
-func liveReq(ctx context.Context, verb, lastEventID, uri string) (*http.Request, error) {
-	req, err := GetReq(ctx, verb, uri)
+//liveReq builds the request for a connection to uri, using GetReq unless the
+//caller supplied tmpl through NotifyRequest.
+func liveReq(ctx context.Context, tmpl *http.Request, lastEventID, uri string, opts *Options, queryResume bool) (*http.Request, error) {
+	var (
+		req *http.Request
+		err error
+	)
+	if tmpl == nil {
+		req, err = GetReq(ctx, "GET", uri)
+	} else {
+		req, err = cloneReq(ctx, tmpl, uri)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if lastEventID != "" {
-		req.Header.Set("Last-Event-ID", lastEventID)
+	for k, v := range opts.Header {
+		if http.CanonicalHeaderKey(k) != "Accept" {
+			req.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+		}
+	}
+	if lastEventID != "" && !opts.NoReplay {
+		if !queryResume {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		if opts.ResumeQueryParam != "" && (queryResume || opts.ResumeIgnoredHeader == "") {
+			q := req.URL.Query()
+			q.Set(opts.ResumeQueryParam, lastEventID)
+			req.URL.RawQuery = q.Encode()
+		}
+	}
+	if opts.InjectHeaders != nil {
+		opts.InjectHeaders(ctx, req.Header)
+	}
+	if !opts.PreserveAcceptHeader || req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "text/event-stream")
 	}
-	req.Header.Set("Accept", "text/event-stream")
 
 	return req, nil
 }
 
+//cloneReq copies tmpl for a new connection to uri, with a fresh body from
+//tmpl.GetBody if it has one.
+func cloneReq(ctx context.Context, tmpl *http.Request, uri string) (*http.Request, error) {
+	req := tmpl.Clone(ctx)
+	if tmpl.GetBody != nil {
+		body, err := tmpl.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("error getting request body: %w", err)
+		}
+		req.Body = body
+	}
+	if uri != tmpl.URL.String() { // moved on through Options.NextField
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		req.URL, req.Host = u, ""
+	}
+	return req, nil
+}
+
+//TransportError is returned when the http.Client fails to perform a request,
+//e.g. because of a DNS, dial or TLS failure, as opposed to the server
+//responding with an error.
+type TransportError struct {
+	URI string
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("error performing request for %s: %v", e.URI, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+//MalformedLineError is returned with Options.Strict for a line that is
+//neither a comment nor a field, e.g. stray plain text.
+type MalformedLineError struct {
+	URI  string
+	Line string
+}
+
+func (e *MalformedLineError) Error() string {
+	return fmt.Sprintf("%s sent malformed line %q", e.URI, e.Line)
+}
+
+//StatusError is returned when the server responds with a status other than
+//200 OK.
+type StatusError struct {
+	URI        string
+	StatusCode int
+
+	//RetryAfter is the wait requested by the response's Retry-After header,
+	//in either of its forms, or 0 if it has none. When the status is retried
+	//through Options.RetryOnStatus, it replaces the reconnection time for
+	//that one attempt.
+	RetryAfter time.Duration
+
+	//Body holds the start of the response body, up to maxStatusBody bytes,
+	//as servers often explain the error there.
+	Body []byte
+}
+
+//maxStatusBody is how much of the body of a response with an unexpected
+//status is kept in StatusError.Body.
+const maxStatusBody = 4 << 10
+
+func (e *StatusError) Error() string {
+	if body := bytes.TrimSpace(e.Body); len(body) != 0 {
+		return fmt.Sprintf("%s returned unexpected status: %d: %s", e.URI, e.StatusCode, body)
+	}
+	return fmt.Sprintf("%s returned unexpected status: %d", e.URI, e.StatusCode)
+}
+
+//MessageEventType is the type the browser EventSource gives events without
+//an event: field. Set Options.DefaultEventType to it for the same behaviour.
+const MessageEventType = "message"
+
 //Event is a go representation of an http server-sent event
 type Event struct {
 	URI  string
 	ID   string
 	Type string
 	Data []byte
+
+	//Seq numbers the events of a stream across reconnects, and ConnIndex
+	//those received on the current connection, both starting at 0. They are
+	//only set if Options.Sequence is.
+	Seq       uint64
+	ConnIndex uint64
+
+	//RawType is the type as sent by the server, before Options.NormalizeType
+	//was applied to get Type. It is only set if NormalizeType is.
+	RawType string
+
+	//Truncated is set if Data was cut short at Options.MaxEventBytes, see
+	//OversizeTruncate.
+	Truncated bool
+
+	//Header holds the headers of the response the event arrived on, e.g. for
+	//tenant IDs or tracing. All events of one connection share the same
+	//Header, which must not be modified. It is nil for events not read from
+	//an HTTP response, such as those of NotifyFile.
+	Header http.Header
+
+	//Synthetic is set on events made up by this package instead of received
+	//from the server, such as the ticks of Options.TickInterval and the
+	//comments delivered with CommentDeliverSynthetic. Their Type
+	//alone does not tell them apart, since a server may send any type.
+	Synthetic bool
 }
 
 //GetReq is a function to return a single request. It will be used by notify to
 //get a request and can be replaces if additional configuration is desired on
-//the request. The "Accept" header will necessarily be overwritten.
+//the request. The "Accept" header will be overwritten unless
+//Options.PreserveAcceptHeader is set.
 var GetReq = func(ctx context.Context, verb, uri string) (*http.Request, error) {
 	return http.NewRequestWithContext(ctx, verb, uri, nil)
 }
@@ -70,130 +208,697 @@ var GetReq = func(ctx context.Context, verb, uri string) (*http.Request, error)
 //Notify takes the uri of an SSE stream and channel, and will send an Event
 //down the channel when received, until the stream is closed. It will then
 //close the stream. This is blocking, and so you will likely want to call this
-//in a new goroutine (via `go Notify(..)`)
-func Notify(ctx context.Context, uri string, retry bool, evCh chan<- *Event) (err error) {
-	if evCh == nil {
-		return ErrNilChan
+//in a new goroutine (via `go Notify(..)`). If ctx is cancelled through
+//context.WithCancelCause, the returned error reports the given cause.
+func Notify(ctx context.Context, uri string, retry bool, evCh chan<- *Event) error {
+	return NotifyWithOptions(ctx, uri, retry, evCh, nil)
+}
+
+//NotifyWithOptions is like Notify, but configures the stream according to
+//opts. A nil opts is equivalent to the zero Options.
+func NotifyWithOptions(ctx context.Context, uri string, retry bool, evCh chan<- *Event, opts *Options) error {
+	_, _, err := NotifyResumable(ctx, uri, retry, evCh, opts)
+	return err
+}
+
+//NotifyRequest is like Notify, but connects with a copy of req instead of the
+//GET request from GetReq, for streams opened with e.g. a POST and a JSON body.
+//The headers needed for the stream, such as Accept and Last-Event-ID, are set
+//on each copy. Reconnects resend the body through req.GetBody, which
+//http.NewRequest sets for the common body types.
+func NotifyRequest(ctx context.Context, req *http.Request, retry bool, evCh chan<- *Event) error {
+	s := newSession(req.URL.String(), Target{Chan: evCh}, nil)
+	s.req = req
+	_, _, err := notify(ctx, retry, s)
+	return err
+}
+
+//NotifyResumable is like NotifyWithOptions, but additionally returns the last
+//event ID and reconnection time in effect when the stream stopped, for
+//whatever reason, so that the caller can persist them and resume later. The
+//reconnection time requested by the server through retry: fields is
+//reported even if retry is false and Notify itself never reconnects.
+func NotifyResumable(ctx context.Context, uri string, retry bool, evCh chan<- *Event, opts *Options) (lastID string, wait time.Duration, err error) {
+	return notify(ctx, retry, newSession(uri, Target{Chan: evCh}, opts))
+}
+
+//notify runs the stream described by s until it stops.
+func notify(ctx context.Context, retry bool, s *session) (lastID string, wait time.Duration, err error) {
+	if err := s.t.validate(); err != nil {
+		return "", defaultWait, err
 	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	if s.opts.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, s.opts.MaxRuntime, errMaxRuntime)
+		defer cancel()
+		defer func() {
+			if errors.Is(err, errMaxRuntime) {
+				err = nil // stopped as planned
+			}
+		}()
+	}
+	s.client = s.opts.client()
+	if s.opts.ProxyCredentials != nil {
+		s.client = withProxyCredentials(s.client, s.opts.ProxyCredentials)
+	}
+	if c := s.client; c.Timeout != 0 {
+		s.logf(LogError, "warning: Client.Timeout of %s will end the stream after that time, see the Client documentation", c.Timeout)
+	}
 
 	var (
-		wait = defaultWait
-		id   string
-		req  *http.Request
+		uri  = s.uri
+		opts = s.opts
 		res  *http.Response
+		body io.Reader
+	)
+	if opts.TickInterval > 0 && s.t.Chan != nil {
+		defer s.startTicks(ctx, opts.TickInterval)()
+	}
+	var (
+		attempt   int
+		reconnect bool // false when following NextField, which is not a reconnect
 	)
 	for {
-		req, err = liveReq(ctx, "GET", id, uri)
+		if reconnect {
+			attempt++
+			if opts.OnReconnect != nil {
+				if opts.NoReplay {
+					opts.OnReconnect("")
+				} else {
+					opts.OnReconnect(s.id)
+				}
+			}
+			if m := opts.Metrics; m != nil && m.Reconnect != nil {
+				m.Reconnect(attempt, s.cause)
+			}
+		}
+		reconnect = true
+		connCtx, cancelConn := context.WithCancelCause(ctx)
+		err = nil
+		if opts.PreConnect != nil {
+			err = opts.PreConnect(connCtx)
+		}
+		if err == nil {
+			var connTimer *time.Timer
+			if opts.ConnectTimeout > 0 {
+				connTimer = time.AfterFunc(opts.ConnectTimeout, func() { cancelConn(ErrConnectTimeout) })
+			}
+			res, body, err = connect(connCtx, s.client, s.req, uri, s.id, opts, s.queryResume)
+			if connTimer != nil && !connTimer.Stop() && err == nil {
+				// the timeout fired just as the response arrived
+				res.Body.Close()
+				err = ErrConnectTimeout
+			}
+		}
 		if err != nil {
-			return fmt.Errorf("error getting sse request: %v", err)
+			cancelConn(nil)
+			if !retry || ctx.Err() != nil || !s.shouldReconnect(err) {
+				return s.id, s.wait, err
+			}
+			s.logf(LogError, "error: %s, reconnecting", err.Error())
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) {
+				s.retryAfter = statusErr.RetryAfter
+			}
+			wait := s.nextWait()
+			s.addReconnect("connection failed", err, wait)
+			if err := s.pause(ctx, wait); err != nil {
+				return s.id, s.wait, err
+			}
+			s.failures++
+			continue
 		}
 
-		res, err = Client.Do(req)
-		if err != nil {
-			return fmt.Errorf("error performing request for %s: %v", uri, err)
+		s.logf(LogInfo, "connected, reading lines")
+		s.failures = 0
+		s.header = res.Header
+		if opts.ResetRetry {
+			s.wait = defaultWait
 		}
-		defer func() {
-			if res == nil || res.Body == nil {
-				return
+		if opts.ResumeIgnoredHeader != "" && opts.ResumeQueryParam != "" && !s.queryResume && res.Header.Get(opts.ResumeIgnoredHeader) != "" {
+			s.logf(LogInfo, "%s ignores the Last-Event-ID header, resuming through ?%s from now on", uri, opts.ResumeQueryParam)
+			s.queryResume = true
+		}
+		if res.Close && !s.warnedClose && retry {
+			s.logf(LogInfo, "warning: %s sent Connection: close, so each reconnect needs a new connection", uri)
+			s.warnedClose = true
+		}
+		if opts.OnConnect != nil {
+			opts.OnConnect(res)
+		}
+		if opts.FirstEventTimeout > 0 {
+			s.firstEvent = time.AfterFunc(opts.FirstEventTimeout, func() { cancelConn(ErrFirstEventTimeout) })
+		}
+		if opts.ReadTimeout > 0 {
+			s.readTimer = time.AfterFunc(opts.ReadTimeout, func() {
+				if opts.OnStale != nil {
+					opts.OnStale()
+				}
+				cancelConn(ErrReadTimeout)
+			})
+		}
+		err = s.loop(ctx, body)
+		if err == nil {
+			s.logf(LogDebug, "stream ended")
+			// trailers are only filled in once the body has been read to the end
+			if opts.OnTrailer != nil && len(res.Trailer) != 0 {
+				opts.OnTrailer(res.Trailer)
 			}
-			if e := res.Body.Close(); err == nil { // prioritize err over e
-				err = e
+		}
+		if s.firstEvent != nil {
+			s.firstEvent.Stop()
+			s.firstEvent = nil
+		}
+		if s.readTimer != nil {
+			s.readTimer.Stop()
+			s.readTimer = nil
+		}
+		// report why the connection was dropped, e.g. ErrFirstEventTimeout
+		err = causeOf(connCtx, err)
+		if e := res.Body.Close(); err == nil { // prioritize err over e
+			err = e
+		}
+		cancelConn(nil)
+		if s.halt != nil {
+			return s.id, s.wait, s.halt
+		}
+		if next := s.next; next != "" {
+			s.next = ""
+			if err == nil && ctx.Err() == nil {
+				s.logf(LogInfo, "stream continues at %s", next)
+				s.uri, uri = next, next
+				s.cause = nil
+				reconnect = false
+				continue
 			}
-		}()
-
-		if res.StatusCode != 200 {
-			return fmt.Errorf("%s returned unexpected status: %d", uri, res.StatusCode)
 		}
-		contenttype := res.Header.Get("Content-Type")
-		if contenttype != "text/event-stream" {
-			return fmt.Errorf("%s returned unexpected Content-Type: %s", uri, contenttype)
+		if s.closedAt.IsZero() {
+			s.closedAt = time.Now()
 		}
-
-		Logger.Print("connected, reading lines")
-		wait, id, err = loop(res.Body, uri, wait, id, evCh)
 		if !retry {
-			return
+			return s.id, s.wait, causeOf(ctx, err)
 		}
-		select {
-		case <-ctx.Done():
-			break
-		default: // log error, then just continue loop
-			if err != nil {
-				Logger.Printf("error: %s, reconnecting", err.Error())
-			}
+		if ctx.Err() != nil {
+			// err, if any, already is the cause, see causeOf
+			return s.id, s.wait, context.Cause(ctx)
+		}
+		if err != nil {
+			s.logf(LogError, "error: %s, reconnecting", err.Error())
 		}
-
 		// wait before reconnecting according to the current reconnection time
-		time.Sleep(wait)
+		wait := s.nextWait()
+		if err != nil {
+			s.addReconnect("connection lost", err, wait)
+		} else {
+			s.addReconnect("stream ended", nil, wait)
+		}
+		if err := s.pause(ctx, wait); err != nil {
+			return s.id, s.wait, err
+		}
 	}
 }
 
-func loop(body io.Reader, uri string, wait time.Duration, id string, evCh chan<- *Event) (time.Duration, string, error) {
+//connect performs a single request for the stream at uri, checks that the
+//response is an event stream and returns it along with its decoded body.
+func connect(ctx context.Context, client *http.Client, tmpl *http.Request, uri, lastID string, opts *Options, queryResume bool) (*http.Response, io.Reader, error) {
+	req, err := liveReq(ctx, tmpl, lastID, uri, opts, queryResume)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting sse request: %w", err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, &TransportError{URI: uri, Err: requestErr(ctx, err)}
+	}
+
+	if res.StatusCode != 200 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxStatusBody)) // best effort
+		res.Body.Close()
+		return nil, nil, &StatusError{
+			URI:        uri,
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+			Body:       body,
+		}
+	}
+	contenttype := res.Header.Get("Content-Type")
+	// parameters such as charset are allowed, and the type is lowercased
+	if mediatype, _, err := mime.ParseMediaType(contenttype); err != nil || mediatype != "text/event-stream" {
+		res.Body.Close()
+		return nil, nil, fmt.Errorf("%s returned unexpected Content-Type: %s", uri, contenttype)
+	}
+
+	body, err := decodeBody(res.Body, res.Header.Get("Content-Encoding"))
+	if err != nil {
+		res.Body.Close()
+		return nil, nil, fmt.Errorf("%s returned undecodable body: %w", uri, err)
+	}
+
+	return res, body, nil
+}
+
+//causeOf returns the cause of ctx's cancellation if ctx is done, so that callers
+//cancelling through context.WithCancelCause see their own reason instead of a
+//bare context.Canceled. Otherwise err is returned unchanged.
+func causeOf(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return err
+}
+
+//requestErr returns err, the error of a request made with ctx, so that it
+//also matches the cause of ctx's cancellation if that is not ctx.Err()
+//itself, e.g. ErrConnectTimeout. The chain of err, such as a *url.Error, is
+//kept either way.
+func requestErr(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); cause != nil && cause != ctx.Err() && !errors.Is(err, cause) {
+		return fmt.Errorf("%w: %w", cause, err)
+	}
+	return err
+}
+
+//session holds the state of a stream that carries over from one connection
+//to the next.
+type session struct {
+	uri    string
+	req    *http.Request // request to clone for each connection, from NotifyRequest
+	opts   *Options
+	t      Target
+	client *http.Client // makes the requests, see Options.client and Options.ProxyCredentials
+
+	wait       time.Duration // current reconnection time
+	retryAfter time.Duration // wait for the next reconnect only, from StatusError.RetryAfter
+	failures   int           // consecutive failed connection attempts, for Options.BackoffFactor
+	id         string        // last event ID
+
+	closedAt time.Time // when the connection was lost, zero while receiving events
+	prevID   string    // ID of the previous event that had an id: field, for Options.IDOrder
+	dedup    *idWindow // IDs of the last events, for Options.DedupWindow
+
+	firstEvent *time.Timer // enforces Options.FirstEventTimeout until the connection's first event
+	readTimer  *time.Timer // enforces Options.ReadTimeout, reset by each line
+
+	halt error // set when the stream must stop regardless of retry
+
+	history *reconnectHistory // records reconnects for Stream.ReconnectHistory, if set
+	cause   error             // why the last connection or attempt ended, for Metrics.Reconnect
+
+	seq uint64 // sequence number of the next event, for Options.Sequence
+
+	queryResume bool // resume through Options.ResumeQueryParam only, see Options.ResumeIgnoredHeader
+
+	next string // URI continuing the stream, from Options.NextField
+
+	header http.Header // headers of the current response, for Event.Header
+
+	warnedClose bool // whether the server's Connection: close has been warned about
+}
+
+func newSession(uri string, t Target, opts *Options) *session {
+	if opts == nil {
+		opts = &Options{}
+	}
+	s := &session{uri: uri, opts: opts, t: t, wait: defaultWait, id: opts.LastEventID}
+	if opts.DedupWindow > 0 {
+		s.dedup = newIDWindow(opts.DedupWindow)
+	}
+	return s
+}
+
+//loop reads events from body, a single connection's response, until it ends.
+func (s *session) loop(ctx context.Context, body io.Reader) error {
+	if s.opts.MaxEmptyReads > 0 {
+		body = &emptyReadGuard{r: body, max: s.opts.MaxEmptyReads}
+	}
+	if s.opts.RawTap != nil {
+		body = io.TeeReader(body, tap{s.opts.RawTap, s})
+	}
+	if s.opts.FlushOnEOF {
+		body = &eofFlusher{r: body}
+	}
+
 	var (
 		currEvent *Event
-		bs        []byte
+		f         field
 		err       error
-		br        = bufio.NewReader(body)
+		fr        = newFieldReader(body)
+		lim       = &limiter{uri: s.uri, opts: s.opts}
+		idBuf     = s.id // id of the event being assembled; id is only advanced on dispatch
+		heldID    bool   // whether idBuf must not become s.id, as it came from a rejected or duplicate event
+		blk       block  // other state of the block being assembled
+		connIndex uint64 // index of the next event on this connection
+		stats     ParseStats
 	)
+	fr.lr.max = s.opts.MaxLineSize
+	if s.opts.OnStats != nil {
+		defer func() { s.opts.OnStats(stats) }()
+	}
 
 	for {
-		bs, err = br.ReadBytes('\n')
+		f, err = fr.next()
+		if s.readTimer != nil && err == nil {
+			s.readTimer.Reset(s.opts.ReadTimeout)
+		}
+		if m := s.opts.Metrics; m != nil && m.Bytes != nil && err == nil {
+			m.Bytes(len(f.line) + 1) // with its newline
+		}
+		if err == errLineTooLong {
+			return &AbuseError{URI: s.uri, Metric: "line size", Limit: float64(fr.lr.max), Value: float64(len(f.line))}
+		}
+		if err != nil && len(f.line) != 0 {
+			// an unterminated line may have been cut short, so it is never parsed
+			s.logf(LogInfo, "stream ended inside a line, discarding %d bytes", len(f.line))
+			stats.MalformedLines++
+		}
+		if err == io.EOF {
+			return nil // stream closed cleanly
+		}
 		if err != nil {
-			return wait, id, err
+			return err
 		}
 
-		if currEvent != nil && len(bs) == 1 { // implies bs[0] == \n i.e. event is finished
-			Logger.Print("received new event")
+		blank := f.blank()
+		if currEvent == nil && blank {
+			if !heldID {
+				s.id = idBuf
+			}
+			blk = block{}
+			continue // nothing to dispatch
+		}
+		if currEvent != nil && blank { // event is finished
+			s.logf(LogDebug, "received new event")
 			if len(currEvent.Data) != 0 { // remove trailing \n
 				currEvent.Data = currEvent.Data[:len(currEvent.Data)-1]
 			}
-			currEvent.ID = id
-			evCh <- currEvent
+			currEvent.ID = idBuf
+			if s.opts.NormalizeType != nil {
+				currEvent.RawType = currEvent.Type
+				currEvent.Type = s.opts.NormalizeType(currEvent.Type)
+			}
+			if currEvent.Type == "" {
+				currEvent.Type = s.opts.DefaultEventType
+			}
+			if blk.oversize {
+				s.logf(LogError, "dropping event %q exceeding %d bytes", currEvent.ID, s.opts.MaxEventBytes)
+				currEvent, blk = nil, block{}
+				continue
+			}
+			if err := lim.check(currEvent); err != nil {
+				return err
+			}
+			if err := s.verifyLength(currEvent, blk.length); err != nil {
+				return err
+			}
+			if c := s.opts.Checksum; c != nil && blk.checksum != "" {
+				if err := c.verify(s.uri, currEvent, blk.checksum); err != nil {
+					if !c.Drop {
+						return err
+					}
+					s.logf(LogError, "dropping event: %s", err.Error())
+					currEvent, blk = nil, block{}
+					continue
+				}
+			}
+			if s.opts.ValidateUTF8 {
+				s.validateUTF8(currEvent)
+			}
+			if b := s.opts.Base64; b != nil {
+				if err := b.decode(s.uri, currEvent); err != nil {
+					if !b.Drop {
+						return err
+					}
+					s.logf(LogError, "dropping event: %s", err.Error())
+					currEvent, blk = nil, block{}
+					continue
+				}
+			}
+			if s.dedup != nil && blk.idField && idBuf != "" && !s.dedup.add(idBuf) {
+				s.logf(LogDebug, "dropping duplicate event %q", idBuf)
+				heldID = true
+				currEvent, blk = nil, block{}
+				continue
+			}
+			// after all reasons to drop the event, so that only delivered ones are compared
+			if blk.idField && idBuf != "" {
+				s.checkIDOrder(idBuf)
+			}
+			if s.opts.IDUpdatePredicate == nil || s.opts.IDUpdatePredicate(currEvent) {
+				if !heldID {
+					s.id = idBuf
+				}
+			} else {
+				heldID = true
+			}
+			if s.firstEvent != nil {
+				s.firstEvent.Stop()
+			}
+			if !s.closedAt.IsZero() {
+				if s.opts.OnGap != nil {
+					s.opts.OnGap(time.Since(s.closedAt))
+				}
+				s.closedAt = time.Time{}
+			}
+			if s.opts.Sequence {
+				currEvent.Seq, currEvent.ConnIndex = s.seq, connIndex
+				s.seq++
+				connIndex++
+			}
+			if ds := s.opts.StreamData; ds != nil && ds.End != nil {
+				ds.End(currEvent)
+			}
+			if m := s.opts.Metrics; m != nil && m.Event != nil {
+				m.Event(currEvent)
+			}
+			if err := s.deliver(ctx, currEvent); err != nil {
+				s.halt = err
+				return err
+			}
+			stats.Events++
 			currEvent = nil // stop assembling a new event
+			blk = block{}
 			continue
 		}
-		if bs[0] == ':' {
-			Logger.Print("comment, ignoring")
-			continue // comment, do nothing
+		if f.comment() {
+			stats.Comments++
+			if m := s.opts.Metrics; m != nil && m.Comment != nil {
+				m.Comment()
+			}
+			if err := s.comment(ctx, f.line); err != nil {
+				return err
+			}
+			continue
 		}
 
-		Logger.Print("received line of length ", len(bs))
-
-		// if there is more than one delimiter, then the others are part of the value
-		bs = bs[:len(bs)-1] // strip newline included by br.ReadBytes
-		spl := bytes.SplitAfterN(bs, delim, 2)
-		name := strings.TrimRight(string(spl[0]), ":") // don't include the delimiter itself
-		var val []byte
-		if len(spl) > 1 {
-			val = spl[1]
-			if len(val) != 0 && val[0] == ' ' {
-				val = val[1:]
-			}
+		if s.logging(LogDebug) { // avoid boxing len(f.line) on every line
+			s.logf(LogDebug, "received line of length %d", len(f.line))
 		}
 
+		name, val := string(f.name), f.val
+
 		switch name {
 		case rName:
-			i, err := strconv.ParseUint(string(val), 10, 64)
+			wait, err := f.retry()
 			if err != nil {
-				Logger.Printf("failed to parse retry field as unsigned integer: %s, ignoring", err.Error())
+				s.logf(LogInfo, "failed to parse retry field as unsigned integer: %s, ignoring", err.Error())
+				stats.IgnoredRetries++
 				continue // just continue
 			}
-			wait = time.Duration(i) * time.Millisecond
+			s.wait = wait
+			if s.opts.OnRetryChange != nil {
+				s.opts.OnRetryChange(s.wait)
+			}
+			if currEvent == nil && s.opts.EmitMetadataOnlyBlocks {
+				currEvent = s.newEvent()
+			}
 		case iName:
-			id = string(val)
+			if !f.validID() {
+				s.logf(LogInfo, "id field contains NUL, ignoring")
+				continue // as required by the spec
+			}
+			idBuf = string(val)
+			heldID = false
+			blk.idField = true
+			if currEvent == nil && s.opts.EmitMetadataOnlyBlocks {
+				currEvent = s.newEvent()
+			}
 		case eName:
 			if currEvent == nil {
-				currEvent = &Event{URI: uri}
+				currEvent = s.newEvent()
 			}
 			currEvent.Type = string(val)
 		case dName:
 			if currEvent == nil {
-				currEvent = &Event{URI: uri}
+				currEvent = s.newEvent()
+			}
+			if ds := s.opts.StreamData; ds != nil {
+				if ds.Line != nil {
+					ds.Line(val)
+				}
+				continue
+			}
+			if max := s.opts.MaxEventBytes; max > 0 && len(currEvent.Data)+len(val) > max {
+				switch s.opts.OnOversize {
+				case OversizeTruncate:
+					if keep := max - len(currEvent.Data); !currEvent.Truncated && keep > 0 {
+						currEvent.Data = append(currEvent.Data, append(val[:keep:keep], '\n')...)
+					}
+					currEvent.Truncated = true
+				case OversizeSkip:
+					blk.oversize = true
+				default:
+					return &AbuseError{URI: s.uri, Metric: "event size", Limit: float64(max), Value: float64(len(currEvent.Data) + len(val))}
+				}
+				continue
 			}
 			currEvent.Data = append(currEvent.Data, append(val, '\n')...)
+		default:
+			known := false
+			if s.opts.Checksum != nil && name == s.opts.Checksum.Field {
+				blk.checksum = string(val)
+				known = true
+			}
+			if s.opts.LengthField != "" && name == s.opts.LengthField {
+				blk.length = string(val)
+				known = true
+			}
+			if s.opts.NextField != "" && name == s.opts.NextField {
+				s.setNext(string(val))
+				known = true
+			}
+			switch {
+			case known:
+			case bytes.IndexByte(f.line, ':') < 0:
+				stats.MalformedLines++
+				if s.opts.Strict {
+					return &MalformedLineError{URI: s.uri, Line: string(f.line)}
+				}
+			default:
+				stats.UnknownFields++
+			}
 		}
 	}
 }
+
+//deliver passes ev to the target. Options.ReadTimeout is paused meanwhile,
+//since a slow consumer holds up reading, not the server. A timeout that has
+//already fired is left alone.
+func (s *session) deliver(ctx context.Context, ev *Event) error {
+	paused := s.readTimer != nil && s.readTimer.Stop()
+	err := s.t.deliver(ctx, ev)
+	if paused {
+		s.readTimer.Reset(s.opts.ReadTimeout)
+	}
+	return err
+}
+
+//checkIDOrder warns if id does not come after the ID of the previous event
+//that had one, according to Options.IDOrder.
+func (s *session) checkIDOrder(id string) {
+	if s.opts.IDOrder == IDOrderNone {
+		return
+	}
+	prev := s.prevID
+	s.prevID = id
+	if prev == "" {
+		return
+	}
+	if increasing, ok := s.opts.IDOrder.increases(prev, id); ok && !increasing {
+		s.logf(LogInfo, "event id %q does not follow previous id %q", id, prev)
+		if s.opts.OnIDRegression != nil {
+			s.opts.OnIDRegression(prev, id)
+		}
+	}
+}
+
+//setNext records the URI sent in an Options.NextField field, resolved
+//against the current one.
+func (s *session) setNext(ref string) {
+	base, err := url.Parse(s.uri)
+	if err == nil {
+		var next *url.URL
+		if next, err = base.Parse(ref); err == nil {
+			s.next = next.String()
+			return
+		}
+	}
+	s.logf(LogInfo, "failed to parse next URI: %s, ignoring", err.Error())
+}
+
+//eofFlusher ends the stream of r with a blank line at io.EOF, terminating a
+//final line and event that the server did not, see Options.FlushOnEOF.
+type eofFlusher struct {
+	r    io.Reader
+	tail []byte // left of the blank line once r is at io.EOF
+}
+
+func (f *eofFlusher) Read(p []byte) (int, error) {
+	if f.tail == nil {
+		n, err := f.r.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		f.tail = []byte("\n\n")
+		if n > 0 {
+			return n, nil
+		}
+	}
+	if len(f.tail) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, f.tail)
+	f.tail = f.tail[n:]
+	return n, nil
+}
+
+//emptyReadGuard fails reads from r with io.ErrNoProgress once it returns no
+//data and no error max times in a row.
+type emptyReadGuard struct {
+	r        io.Reader
+	max, run int
+}
+
+func (g *emptyReadGuard) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	if n > 0 || err != nil || len(p) == 0 {
+		g.run = 0
+		return n, err
+	}
+	if g.run++; g.run >= g.max {
+		return 0, io.ErrNoProgress
+	}
+	return 0, nil
+}
+
+//tap writes to w, ignoring errors so that they do not affect parsing.
+type tap struct {
+	w io.Writer
+	s *session
+}
+
+func (t tap) Write(p []byte) (int, error) {
+	if _, err := t.w.Write(p); err != nil {
+		t.s.logf(LogError, "error writing to raw tap: %s, ignoring", err.Error())
+	}
+	return len(p), nil
+}
+
+//block holds what is known about the block of fields making up the event
+//being assembled, besides the event itself.
+type block struct {
+	idField  bool   // whether it has an id: field
+	checksum string // value of its Options.Checksum field
+	length   string // value of its Options.LengthField field
+	oversize bool   // whether its data exceeds Options.MaxEventBytes, with OversizeSkip
+}
+
+//newEvent starts assembling a new event.
+func (s *session) newEvent() *Event {
+	if ds := s.opts.StreamData; ds != nil && ds.Start != nil {
+		ds.Start()
+	}
+	return &Event{URI: s.uri, Header: s.header}
+}
@@ -3,12 +3,17 @@ package sse
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -77,8 +82,46 @@ data: event 2
 retry: 2000
 
 `
+
+	// tests: only the first colon is the delimiter, the others are part of the value
+	colonStream = `data::x
+id::y
+event::z
+
+`
+
+	// tests: a value may contain colons, only a single leading space is stripped
+	urlStream = `data: http://example.com:8080/a:b
+data:  two spaces
+
+`
+
+	// tests: a leading tab is kept, only a space is stripped
+	tabStream = "data:\tone\ndata: \ttwo\ndata: \ndata:  \n\n"
+
+	// tests: a field name without a colon has an empty value
+	bareFieldStream = "data\nid\n\ndata: x\ndata\n\n"
+
+	// tests: blank lines ending in CRLF dispatch, as sent by some servers
+	crlfBlankStream = "id: 1\ndata: event 1\n\r\nid: 2\ndata: event 2\n\r\n"
+
+	// tests: lines may end in CR, CRLF or LF
+	crStream    = "id: 1\rdata: event 1\rdata: line 2\r\rdata: event 2\r\r"
+	crlfStream  = "id: 1\r\ndata: event 1\r\ndata: line 2\r\n\r\ndata: event 2\r\n\r\n"
+	mixedStream = "id: 1\rdata: event 1\r\ndata: line 2\n\r\n\rdata: event 2\n\r"
+
+	// tests: id fields containing NUL are ignored
+	nulIDStream = "id: 1\ndata: event 1\n\nid: 2\x003\ndata: event 2\n\n"
+
+	// tests: a leading byte order mark is stripped
+	bomStream = "\ufeffdata: hello\n\n"
 )
 
+var lineEndingEvents = []*Event{
+	{ID: "1", Data: []byte("event 1\nline 2")},
+	{ID: "1", Data: []byte("event 2")},
+}
+
 func TestEventStream(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -143,6 +186,73 @@ func TestEventStream(t *testing.T) {
 			},
 			wait: 2000 * time.Millisecond,
 		},
+		{
+			name:   "colonStream",
+			stream: colonStream,
+			events: []*Event{
+				{Data: []byte(":x"), ID: ":y", Type: ":z"},
+			},
+		},
+		{
+			name:   "urlStream",
+			stream: urlStream,
+			events: []*Event{
+				{Data: []byte("http://example.com:8080/a:b\n two spaces")},
+			},
+		},
+		{
+			name:   "tabStream",
+			stream: tabStream,
+			events: []*Event{
+				{Data: []byte("\tone\n\ttwo\n\n ")},
+			},
+		},
+		{
+			name:   "bareFieldStream",
+			stream: bareFieldStream,
+			events: []*Event{
+				{Data: []byte{}},
+				{Data: []byte("x\n")},
+			},
+		},
+		{
+			name:   "crlfBlankStream",
+			stream: crlfBlankStream,
+			events: []*Event{
+				{Data: []byte("event 1"), ID: "1"},
+				{Data: []byte("event 2"), ID: "2"},
+			},
+		},
+		{
+			name:   "crStream",
+			stream: crStream,
+			events: lineEndingEvents,
+		},
+		{
+			name:   "crlfStream",
+			stream: crlfStream,
+			events: lineEndingEvents,
+		},
+		{
+			name:   "mixedStream",
+			stream: mixedStream,
+			events: lineEndingEvents,
+		},
+		{
+			name:   "nulIDStream",
+			stream: nulIDStream,
+			events: []*Event{
+				{Data: []byte("event 1"), ID: "1"},
+				{Data: []byte("event 2"), ID: "1"},
+			},
+		},
+		{
+			name:   "bomStream",
+			stream: bomStream,
+			events: []*Event{
+				{Data: []byte("hello")},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -159,9 +269,10 @@ func TestEventStream(t *testing.T) {
 				if tt.wait != 0 {
 					expectedWait = tt.wait
 				}
-				wait, _, err := loop(bytes.NewReader([]byte(tt.stream)), "", defaultWait, "", evCh)
+				s := newSession("", Target{Chan: evCh}, &Options{})
+				err := s.loop(context.Background(), bytes.NewReader([]byte(tt.stream)))
 				assert.NoError(t, err)
-				assert.Equal(t, expectedWait, wait)
+				assert.Equal(t, expectedWait, s.wait)
 				close(evCh)
 				wg.Done()
 			}()
@@ -178,6 +289,141 @@ func TestEventStream(t *testing.T) {
 	}
 }
 
+func TestMetadataOnlyBlocks(t *testing.T) {
+	const stream = "id: 1\n\ndata: event 2\n\n"
+
+	tests := []struct {
+		name   string
+		opts   *Options
+		events []*Event
+	}{
+		{
+			name: "not emitted by default",
+			opts: &Options{},
+			events: []*Event{
+				{Data: []byte("event 2"), ID: "1"},
+			},
+		},
+		{
+			name: "emitted",
+			opts: &Options{EmitMetadataOnlyBlocks: true},
+			events: []*Event{
+				{ID: "1"},
+				{Data: []byte("event 2"), ID: "1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				events []*Event
+				evCh   = make(chan *Event, len(tt.events)+1)
+			)
+			s := newSession("", Target{Chan: evCh}, tt.opts)
+			require.NoError(t, s.loop(context.Background(), strings.NewReader(stream)))
+			assert.Equal(t, "1", s.id)
+			close(evCh)
+			for event := range evCh {
+				events = append(events, event)
+			}
+
+			require.Equal(t, tt.events, events)
+		})
+	}
+}
+
+func TestDefaultEventType(t *testing.T) {
+	const stream = "data: untyped\n\nevent: update\ndata: typed\n\n"
+
+	route := func(opts *Options) []string {
+		var (
+			routed []string
+			evCh   = make(chan *Event, 2)
+		)
+		require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+		close(evCh)
+		for event := range evCh {
+			switch event.Type {
+			case "message":
+				routed = append(routed, "message:"+string(event.Data))
+			case "update":
+				routed = append(routed, "update:"+string(event.Data))
+			default:
+				routed = append(routed, "default:"+string(event.Data))
+			}
+		}
+		return routed
+	}
+
+	assert.Equal(t, []string{"default:untyped", "update:typed"}, route(&Options{}))
+	assert.Equal(t, []string{"message:untyped", "update:typed"}, route(&Options{DefaultEventType: "message"}))
+}
+
+func TestEventTypeResetPerEvent(t *testing.T) {
+	const stream = "event: update\ndata: typed\n\ndata: untyped\n\nevent: update\ndata: typed again\n\ndata: untyped again\n\n"
+
+	types := func(opts *Options) []string {
+		evCh := make(chan *Event, 4)
+		require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+		close(evCh)
+		var types []string
+		for event := range evCh {
+			types = append(types, event.Type+":"+string(event.Data))
+		}
+		return types
+	}
+
+	assert.Equal(t, []string{"update:typed", ":untyped", "update:typed again", ":untyped again"}, types(nil))
+	assert.Equal(t, []string{"update:typed", "message:untyped", "update:typed again", "message:untyped again"}, types(&Options{DefaultEventType: MessageEventType}))
+}
+
+func TestConnectionReset(t *testing.T) {
+	var (
+		stream = io.MultiReader(
+			strings.NewReader("data: complete\n\ndata: cut sh"),
+			iotest.ErrReader(syscall.ECONNRESET),
+		)
+		evCh = make(chan *Event, 2)
+	)
+
+	err := newSession("", Target{Chan: evCh}, &Options{}).loop(context.Background(), stream)
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+	close(evCh)
+
+	var events []*Event
+	for event := range evCh {
+		events = append(events, event)
+	}
+	require.Equal(t, []*Event{{Data: []byte("complete")}}, events)
+}
+
+func TestStreamData(t *testing.T) {
+	const stream = "event: big\ndata: a\ndata: b\ndata: c\n\n: comment\ndata: d\n\n"
+
+	var (
+		signals []string
+		evCh    = make(chan *Event, 2)
+		opts    = &Options{StreamData: &DataStreamer{
+			Start: func() { signals = append(signals, "start") },
+			Line:  func(line []byte) { signals = append(signals, "line "+string(line)) },
+			End:   func(ev *Event) { signals = append(signals, "end "+ev.Type) },
+		}}
+	)
+	require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+	close(evCh)
+
+	assert.Equal(t, []string{
+		"start", "line a", "line b", "line c", "end big",
+		"start", "line d", "end ",
+	}, signals)
+	var events []*Event
+	for event := range evCh {
+		events = append(events, event)
+	}
+	assert.Equal(t, []*Event{{Type: "big"}, {}}, events)
+}
+
 func TestReconnect(t *testing.T) {
 	server := disconnectingServer(t)
 	defer server.Close()
@@ -190,8 +436,12 @@ func TestReconnect(t *testing.T) {
 	wg.Add(2)
 	go func() {
 		err := Notify(context.Background(), server.URL, true, evCh)
-		assert.Error(t, err)
-		assert.True(t, strings.HasSuffix(err.Error(), strconv.Itoa(204)))
+		var statusErr *StatusError
+		if assert.ErrorAs(t, err, &statusErr) {
+			assert.Equal(t, 204, statusErr.StatusCode)
+			assert.Equal(t, server.URL, statusErr.URI)
+			assert.Equal(t, server.URL+" returned unexpected status: 204", err.Error())
+		}
 		close(evCh)
 		wg.Done()
 	}()
@@ -203,16 +453,82 @@ func TestReconnect(t *testing.T) {
 	}()
 	wg.Wait()
 
+	require.Len(t, events, 1)
+	assert.Equal(t, "text/event-stream", events[0].Header.Get("Content-Type"))
 	require.Equal(t,
 		[]*Event{{
-			Data: []byte("event 1"),
-			URI:  server.URL,
-			ID:   "myid",
+			Data:   []byte("event 1"),
+			URI:    server.URL,
+			ID:     "myid",
+			Header: events[0].Header,
 		}},
 		events,
 	)
 }
 
+func TestStatusErrorBody(t *testing.T) {
+	const body = `{"error": "invalid_token", "message": "token expired"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		_, err := w.Write([]byte(body + "\n" + strings.Repeat(" ", 2*maxStatusBody)))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	err := Notify(context.Background(), server.URL, true, make(chan *Event))
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 400, statusErr.StatusCode)
+	assert.Len(t, statusErr.Body, maxStatusBody, "the body is capped")
+	assert.True(t, bytes.HasPrefix(statusErr.Body, []byte(body)))
+	assert.Equal(t, server.URL+" returned unexpected status: 400: "+body, err.Error())
+}
+
+func TestMetrics(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1, 3:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 10\r\nid: myid\ndata: event\n\n"))
+			assert.NoError(t, err)
+		case 2:
+			w.WriteHeader(503)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	type reconnect struct {
+		attempt int
+		failed  bool
+	}
+	var (
+		events     int
+		bytesRead  int
+		reconnects []reconnect
+		opts       = &Options{
+			RetryOnStatus: func(code int) bool { return code == 503 },
+			Metrics: &Metrics{
+				Event: func(*Event) { events++ },
+				Bytes: func(n int) { bytesRead += n },
+				Reconnect: func(attempt int, cause error) {
+					reconnects = append(reconnects, reconnect{attempt, cause != nil})
+				},
+			},
+		}
+	)
+	err := NotifyWithOptions(context.Background(), server.URL, true, make(chan *Event, 2), opts)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 2, events)
+	assert.Equal(t, 2*len("retry: 10\nid: myid\ndata: event\n\n"), bytesRead)
+	assert.Equal(t, []reconnect{{1, false}, {2, true}, {3, false}}, reconnects)
+}
+
 func TestDisconnect(t *testing.T) {
 	server, done := waitingServer(t)
 	defer server.Close()
@@ -241,26 +557,1313 @@ func TestDisconnect(t *testing.T) {
 	wg.Wait()
 }
 
-func disconnectingServer(t *testing.T) *httptest.Server {
-	var count int
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch count {
-		case 0:
+func TestDisconnectCause(t *testing.T) {
+	server, done := waitingServer(t)
+	defer server.Close()
+
+	var (
+		evCh        = make(chan *Event)
+		wg          = sync.WaitGroup{}
+		ctx, cancel = context.WithCancelCause(context.Background())
+	)
+
+	wg.Add(2)
+	go func() {
+		err := Notify(ctx, server.URL, true, evCh)
+		assert.Error(t, err)
+		assert.True(t, strings.HasSuffix(err.Error(), "shutting down"))
+		wg.Done()
+	}()
+	go func() {
+		<-done
+		wg.Done()
+	}()
+
+	// give Notify time to send its request
+	time.Sleep(100 * time.Millisecond)
+	cancel(errors.New("shutting down"))
+	wg.Wait()
+}
+
+func TestNotifyResumable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\nid: 1\ndata: event 1\n\nid: 2\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var (
+		evCh        = make(chan *Event)
+		ctx, cancel = context.WithCancel(context.Background())
+		lastID      string
+		wait        time.Duration
+		err         error
+		done        = make(chan struct{})
+	)
+	go func() {
+		lastID, wait, err = NotifyResumable(ctx, server.URL, true, evCh, nil)
+		close(done)
+	}()
+
+	<-evCh
+	last := <-evCh
+	cancel()
+	<-done
+
+	assert.Error(t, err)
+	assert.Equal(t, last.ID, lastID)
+	assert.Equal(t, "2", lastID)
+	assert.Equal(t, 10*time.Millisecond, wait)
+}
+
+func TestPreserveAcceptHeader(t *testing.T) {
+	const accept = "application/vnd.example+event-stream"
+
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/event-stream")
+	}))
+	defer server.Close()
+
+	defaultGetReq := GetReq
+	defer func() { GetReq = defaultGetReq }()
+	GetReq = func(ctx context.Context, verb, uri string) (*http.Request, error) {
+		req, err := defaultGetReq(ctx, verb, uri)
+		if err == nil {
+			req.Header.Set("Accept", accept)
+		}
+		return req, err
+	}
+
+	evCh := make(chan *Event)
+	require.NoError(t, Notify(context.Background(), server.URL, false, evCh))
+	require.NoError(t, NotifyWithOptions(context.Background(), server.URL, false, evCh, &Options{PreserveAcceptHeader: true}))
+	assert.Equal(t, []string{"text/event-stream", accept}, got)
+}
+
+func TestResumeQueryParam(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch len(queries) {
+		case 1:
 			w.Header().Set("Content-Type", "text/event-stream")
-			_, err := w.Write([]byte("data: event 1\nretry: 100\nid: myid\n\n"))
+			_, err := w.Write([]byte("data: event 1\nretry: 10\nid: 42\n\n"))
 			assert.NoError(t, err)
-			count++
 		default:
-			require.Equal(t, "myid", r.Header.Get("Last-Event-ID"))
 			w.WriteHeader(204)
 		}
 	}))
+	defer server.Close()
+
+	evCh := make(chan *Event, 1)
+	err := NotifyWithOptions(context.Background(), server.URL+"?topic=a&since=0", true, evCh, &Options{ResumeQueryParam: "since"})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"topic=a&since=0", "since=42&topic=a"}, queries)
 }
 
-func waitingServer(t *testing.T) (*httptest.Server, chan struct{}) {
-	done := make(chan struct{})
-	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		<-r.Context().Done() // wait for the client to go away
-		done <- struct{}{}
-	})), done
+func TestResumeIgnoredHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		ignores bool
+		param   string
+		headers []string
+		queries []string
+	}{
+		{"honors header", false, "since", []string{"", "1", "2"}, []string{"", "", ""}},
+		{"ignores header", true, "since", []string{"", "", ""}, []string{"", "since=1", "since=2"}},
+		// without a query parameter to switch to, the header is kept
+		{"no query param", true, "", []string{"", "1", "2"}, []string{"", "", ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var headers, queries []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers = append(headers, r.Header.Get("Last-Event-ID"))
+				queries = append(queries, r.URL.RawQuery)
+				if len(headers) > 2 {
+					w.WriteHeader(204)
+					return
+				}
+				if test.ignores {
+					w.Header().Set("X-Resume-Ignored", "true")
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, err := w.Write([]byte("retry: 10\nid: " + strconv.Itoa(len(headers)) + "\ndata: event\n\n"))
+				assert.NoError(t, err)
+			}))
+			defer server.Close()
+
+			evCh := make(chan *Event, 2)
+			opts := &Options{ResumeQueryParam: test.param, ResumeIgnoredHeader: "X-Resume-Ignored"}
+			assert.Error(t, NotifyWithOptions(context.Background(), server.URL, true, evCh, opts))
+			assert.Equal(t, test.headers, headers)
+			assert.Equal(t, test.queries, queries)
+		})
+	}
+}
+
+func TestOnReconnect(t *testing.T) {
+	var (
+		lastEventIDs []string
+		streams      = []string{
+			"retry: 10\nid: 1\ndata: a\n\n",
+			"id: 2\ndata: b\n\n",
+			"id\ndata: c\n\n",
+			"data: d\n\n",
+		}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		if len(lastEventIDs) > len(streams) {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte(streams[len(lastEventIDs)-1]))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		reported []string
+		evCh     = make(chan *Event, len(streams))
+		opts     = &Options{OnReconnect: func(id string) { reported = append(reported, id) }}
+	)
+	assert.Error(t, NotifyWithOptions(context.Background(), server.URL, true, evCh, opts))
+	assert.Equal(t, []string{"1", "2", "", ""}, reported)
+	assert.Equal(t, reported, lastEventIDs[1:])
+}
+
+func TestNextField(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/segments/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 5000\nid: 1\ndata: event 1\n\nid: 2\ndata: event 2\n\nnext: 2\n\n"))
+		assert.NoError(t, err)
+	})
+	mux.HandleFunc("/segments/2", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("id: 3\ndata: event 3\n\n"))
+		assert.NoError(t, err)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var (
+		evCh  = make(chan *Event, 3)
+		start = time.Now()
+	)
+	require.NoError(t, NotifyWithOptions(context.Background(), server.URL+"/segments/1", false, evCh, &Options{NextField: "next"}))
+	assert.Less(t, time.Since(start), time.Second)
+	close(evCh)
+
+	var data, uris []string
+	for ev := range evCh {
+		data = append(data, string(ev.Data))
+		uris = append(uris, ev.URI)
+	}
+	assert.Equal(t, []string{"event 1", "event 2", "event 3"}, data)
+	assert.Equal(t, []string{server.URL + "/segments/1", server.URL + "/segments/1", server.URL + "/segments/2"}, uris)
+}
+
+func TestNextFieldNotReconnect(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch requests {
+		case 1:
+			_, err := w.Write([]byte("retry: 1\ndata: one\n\nnext: /2\n\n"))
+			assert.NoError(t, err)
+		case 2:
+			assert.Equal(t, "/2", r.URL.Path)
+			_, err := w.Write([]byte("data: two\n\n"))
+			assert.NoError(t, err)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	var (
+		reconnects []string
+		attempts   []int
+		evCh       = make(chan *Event, 2)
+	)
+	err := NotifyWithOptions(context.Background(), server.URL+"/1", true, evCh, &Options{
+		NextField:   "next",
+		OnReconnect: func(id string) { reconnects = append(reconnects, id) },
+		Metrics:     &Metrics{Reconnect: func(attempt int, _ error) { attempts = append(attempts, attempt) }},
+	})
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNoContent, statusErr.StatusCode)
+
+	// /1 continues at /2, whose end is the only reconnect
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, []string{""}, reconnects)
+	assert.Equal(t, []int{1}, attempts)
+}
+
+func TestPreConnect(t *testing.T) {
+	var (
+		tickets  int
+		requests []string
+		errAuth  = errors.New("ticket service unavailable")
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("X-Ticket"))
+		if len(requests) > 2 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		ticket string
+		opts   = &Options{
+			PreConnect: func(ctx context.Context) error {
+				tickets++
+				if tickets == 2 {
+					return errAuth
+				}
+				ticket = "ticket-" + strconv.Itoa(tickets)
+				return nil
+			},
+			InjectHeaders:   func(ctx context.Context, h http.Header) { h.Set("X-Ticket", ticket) },
+			ShouldReconnect: func(err error) bool { return errors.Is(err, errAuth) },
+		}
+		evCh = make(chan *Event, 2)
+	)
+	assert.Error(t, NotifyWithOptions(context.Background(), server.URL, true, evCh, opts))
+	assert.Equal(t, 4, tickets)
+	assert.Equal(t, []string{"ticket-1", "ticket-3", "ticket-4"}, requests)
+
+	tickets = 1 // fail the first attempt
+	err := NotifyWithOptions(context.Background(), server.URL, false, evCh, opts)
+	assert.Equal(t, errAuth, err)
+}
+
+func TestConnectionClose(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		open     int
+		requests int
+	)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 3 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Connection", "close")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\nid: " + strconv.Itoa(requests) + "\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch state {
+		case http.StateNew:
+			open++
+		case http.StateClosed, http.StateHijacked:
+			open--
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	Logger.SetOutput(&logs)
+	defer Logger.SetOutput(io.Discard)
+
+	evCh := make(chan *Event, 3)
+	assert.Error(t, NotifyWithOptions(context.Background(), server.URL, true, evCh, nil))
+	close(evCh)
+	var ids []string
+	for ev := range evCh {
+		ids = append(ids, ev.ID)
+	}
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+	assert.Equal(t, 1, strings.Count(logs.String(), "sent Connection: close"))
+
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return open == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestOnRetryChange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 5000\ndata: event 1\n\nretry: soon\nretry: 250\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		changes []time.Duration
+		opts    = &Options{OnRetryChange: func(d time.Duration) { changes = append(changes, d) }}
+	)
+	require.NoError(t, NotifyWithOptions(context.Background(), server.URL, false, make(chan *Event, 2), opts))
+	assert.Equal(t, []time.Duration{5 * time.Second, 250 * time.Millisecond}, changes)
+}
+
+type emptyReader struct {
+	reads int
+}
+
+func (r *emptyReader) Read([]byte) (int, error) {
+	r.reads++
+	return 0, nil
+}
+
+func TestMaxEmptyReads(t *testing.T) {
+	for maxReads, want := range map[int]int{0: 100, 3: 3} {
+		var (
+			r    = &emptyReader{}
+			opts = &Options{MaxEmptyReads: maxReads}
+		)
+		err := newSession("", Target{Chan: make(chan *Event)}, opts).loop(context.Background(), r)
+		assert.Equal(t, io.ErrNoProgress, err)
+		assert.Equal(t, want, r.reads, "MaxEmptyReads %d", maxReads)
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	const stream = "event: Update\ndata: 1\n\nevent: update \ndata: 2\n\nevent: UPDATE\ndata: 3\n\ndata: 4\n\n"
+
+	var (
+		handled  = map[string][]string{}
+		rawTypes []string
+		route    = func(ev *Event) error {
+			handled[ev.Type] = append(handled[ev.Type], string(ev.Data))
+			rawTypes = append(rawTypes, ev.RawType)
+			return nil
+		}
+		opts = &Options{
+			NormalizeType:    func(typ string) string { return strings.ToLower(strings.TrimSpace(typ)) },
+			DefaultEventType: "message",
+		}
+	)
+	require.NoError(t, newSession("", Target{Func: route}, opts).loop(context.Background(), strings.NewReader(stream)))
+	assert.Equal(t, map[string][]string{"update": {"1", "2", "3"}, "message": {"4"}}, handled)
+	assert.Equal(t, []string{"Update", "update ", "UPDATE", ""}, rawTypes)
+}
+
+func TestStrayLines(t *testing.T) {
+	const stream = "data: event 1\n\nSome plain text\n\nid: 2\ndata: a\nWARNING oops\ndata: b\n\ndata\n\n"
+
+	t.Run("lenient", func(t *testing.T) {
+		evCh := make(chan *Event, 4)
+		require.NoError(t, newSession("", Target{Chan: evCh}, nil).loop(context.Background(), strings.NewReader(stream)))
+		close(evCh)
+		var events []*Event
+		for ev := range evCh {
+			events = append(events, ev)
+		}
+		assert.Equal(t, []*Event{
+			{Data: []byte("event 1")},
+			{ID: "2", Data: []byte("a\nb")},
+			{ID: "2", Data: []byte("")},
+		}, events)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		evCh := make(chan *Event, 4)
+		err := newSession("uri", Target{Chan: evCh}, &Options{Strict: true}).loop(context.Background(), strings.NewReader(stream))
+		assert.Equal(t, &MalformedLineError{URI: "uri", Line: "Some plain text"}, err)
+		assert.Len(t, evCh, 1)
+	})
+}
+
+func TestMaxRuntime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 5000\n\n"))
+		assert.NoError(t, err)
+		for i := 1; ; i++ {
+			if _, err := w.Write([]byte("id: " + strconv.Itoa(i) + "\ndata: event\n\n")); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(30 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	var (
+		evCh  = make(chan *Event, 100)
+		start = time.Now()
+	)
+	lastID, wait, err := NotifyResumable(context.Background(), server.URL, true, evCh, &Options{MaxRuntime: 150 * time.Millisecond})
+	require.NoError(t, err)
+	assert.InDelta(t, 150*time.Millisecond, time.Since(start), float64(50*time.Millisecond))
+	assert.Equal(t, 5*time.Second, wait)
+
+	close(evCh)
+	var last *Event
+	for ev := range evCh {
+		last = ev
+	}
+	require.NotNil(t, last)
+	assert.Equal(t, last.ID, lastID)
+}
+
+func TestNotifyRequest(t *testing.T) {
+	type request struct {
+		method, body, accept, lastEventID string
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		requests = append(requests, request{r.Method, string(body), r.Header.Get("Accept"), r.Header.Get("Last-Event-ID")})
+		if len(requests) > 2 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err = w.Write([]byte("retry: 10\nid: " + strconv.Itoa(len(requests)) + "\ndata: token\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	const prompt = `{"prompt": "hello"}`
+	req, err := http.NewRequest("POST", server.URL, strings.NewReader(prompt))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	evCh := make(chan *Event, 2)
+	err = NotifyRequest(context.Background(), req, true, evCh)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Len(t, evCh, 2)
+	assert.Equal(t, []request{
+		{"POST", prompt, "text/event-stream", ""},
+		{"POST", prompt, "text/event-stream", "1"},
+		{"POST", prompt, "text/event-stream", "2"},
+	}, requests)
+	assert.Empty(t, req.Header.Get("Accept"), "the caller's request is not modified")
+}
+
+func TestLastEventID(t *testing.T) {
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("id: 43\ndata: event\n\nid: 44\x00\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	lastID, _, err := NotifyResumable(context.Background(), server.URL, false, make(chan *Event, 2), &Options{LastEventID: "42"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"42"}, lastEventIDs)
+	assert.Equal(t, "43", lastID)
+}
+
+func TestEmptyIDResetsLastEventID(t *testing.T) {
+	for _, field := range []string{"id", "id:", "id: "} {
+		t.Run(field, func(t *testing.T) {
+			evCh := make(chan *Event, 2)
+			s := newSession("", Target{Chan: evCh}, nil)
+			require.NoError(t, s.loop(context.Background(), strings.NewReader("id: 1\ndata: a\n\n"+field+"\ndata: b\n\n")))
+			assert.Equal(t, "1", (<-evCh).ID)
+			assert.Equal(t, "", (<-evCh).ID)
+			assert.Equal(t, "", s.id)
+		})
+	}
+}
+
+func TestEmptyIDOmitsLastEventIDOnReconnect(t *testing.T) {
+	type request struct {
+		sent bool
+		id   string
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, sent := r.Header["Last-Event-Id"]
+		requests = append(requests, request{sent, r.Header.Get("Last-Event-ID")})
+		if len(requests) > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\nid: 43\ndata: a\n\nid\ndata: b\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	lastID, _, err := NotifyResumable(context.Background(), server.URL, true, make(chan *Event, 2), &Options{LastEventID: "42"})
+	assert.Error(t, err)
+	assert.Equal(t, "", lastID)
+	assert.Equal(t, []request{{true, "42"}, {false, ""}}, requests)
+}
+
+func TestCancelWhileStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := Subscribe(ctx, server.URL, true, nil)
+
+	<-s.Events()
+	cancel()
+	for range s.Events() {
+	}
+	assert.ErrorIs(t, s.Err(), context.Canceled)
+	assert.Empty(t, s.ReconnectHistory(), "a cancelled stream is not reconnecting")
+}
+
+func TestEventHeader(t *testing.T) {
+	var n int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Tenant", strconv.Itoa(n))
+		_, err := w.Write([]byte("retry: 1\ndata: event 1\n\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		tenants []string
+		headers []http.Header
+	)
+	fn := func(ev *Event) error {
+		tenants = append(tenants, ev.Header.Get("X-Tenant"))
+		headers = append(headers, ev.Header)
+		if len(tenants) == 4 {
+			return errStopIteration
+		}
+		return nil
+	}
+	assert.Equal(t, errStopIteration, NotifyFunc(context.Background(), server.URL, true, fn))
+	assert.Equal(t, []string{"1", "1", "2", "2"}, tenants)
+	assert.Same(t, &headers[0]["X-Tenant"][0], &headers[1]["X-Tenant"][0], "events of one connection share the header")
+}
+
+func TestOnTrailer(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Trailer", "X-Events")
+		_, err := w.Write([]byte("data: event\n\n"))
+		assert.NoError(t, err)
+		w.Header().Set("X-Events", "1")
+	})
+	for _, proto := range []string{"HTTP/1.1", "HTTP/2.0"} {
+		t.Run(proto, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(handler)
+			server.EnableHTTP2 = true
+			server.StartTLS()
+			defer server.Close()
+
+			defaultClient := Client
+			defer func() { Client = defaultClient }()
+			Client = server.Client()
+			if proto == "HTTP/1.1" {
+				Client.Transport.(*http.Transport).ForceAttemptHTTP2 = false
+				Client.Transport.(*http.Transport).TLSClientConfig.NextProtos = []string{"http/1.1"}
+			}
+
+			var (
+				protos   []string
+				trailers []http.Header
+				opts     = &Options{
+					OnConnect: func(res *http.Response) { protos = append(protos, res.Proto) },
+					OnTrailer: func(trailer http.Header) { trailers = append(trailers, trailer) },
+				}
+			)
+			require.NoError(t, NotifyWithOptions(context.Background(), server.URL, false, make(chan *Event, 1), opts))
+			assert.Equal(t, []string{proto}, protos)
+			assert.Equal(t, []http.Header{{"X-Events": {"1"}}}, trailers)
+		})
+	}
+}
+
+func TestCancelWhileSending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: event\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		connected   = make(chan struct{})
+		done        = make(chan error)
+	)
+	defer cancel()
+	opts := &Options{OnConnect: func(*http.Response) { close(connected) }}
+	go func() {
+		// nobody receives from the unbuffered channel
+		done <- NotifyWithOptions(ctx, server.URL, true, make(chan *Event), opts)
+	}()
+
+	<-connected
+	time.Sleep(20 * time.Millisecond) // let the event reach the send
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("Notify is stuck sending to the channel")
+	}
+}
+
+func TestOnConnect(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("X-Request-Id", "req-"+strconv.Itoa(requests))
+		if requests > 2 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		requestIDs []string
+		opts       = &Options{OnConnect: func(res *http.Response) {
+			requestIDs = append(requestIDs, res.Header.Get("X-Request-Id"))
+		}}
+	)
+	assert.Error(t, NotifyWithOptions(context.Background(), server.URL, true, make(chan *Event, 2), opts))
+	assert.Equal(t, []string{"req-1", "req-2"}, requestIDs, "rejected responses are not reported")
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		ok          bool
+	}{
+		{"text/event-stream", true},
+		{"text/event-stream; charset=utf-8", true},
+		{"Text/Event-Stream", true},
+		{"TEXT/EVENT-STREAM;charset=UTF-8", true},
+		{"text/plain", false},
+		{"text/event-stream-ish", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			_, err := w.Write([]byte("data: event\n\n"))
+			assert.NoError(t, err)
+		}))
+
+		evCh := make(chan *Event, 1)
+		err := Notify(context.Background(), server.URL, false, evCh)
+		if tt.ok {
+			assert.NoError(t, err, tt.contentType)
+			assert.Len(t, evCh, 1, tt.contentType)
+		} else {
+			assert.ErrorContains(t, err, "unexpected Content-Type", tt.contentType)
+		}
+		server.Close()
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+		if len(requests) > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, line := range []string{"retry: 10\n", "data: event\n\n", ": still here\n"} {
+			_, err := w.Write([]byte(line))
+			assert.NoError(t, err)
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+		<-r.Context().Done() // go silent
+	}))
+	defer server.Close()
+
+	var (
+		logs bytes.Buffer
+		evCh = make(chan *Event, 1)
+	)
+	Logger.SetOutput(&logs)
+	defer Logger.SetOutput(io.Discard)
+
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, &Options{ReadTimeout: 100 * time.Millisecond})
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Len(t, requests, 2)
+	assert.InDelta(t, 210*time.Millisecond, requests[1].Sub(requests[0]), float64(50*time.Millisecond))
+	assert.Len(t, evCh, 1)
+	assert.Contains(t, logs.String(), ErrReadTimeout.Error())
+}
+
+func TestOnStale(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\n"))
+		assert.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			_, err := w.Write([]byte(": ping\n"))
+			assert.NoError(t, err)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+		<-r.Context().Done() // heartbeats stop
+	}))
+	defer server.Close()
+
+	var (
+		start = time.Now()
+		stale = make(chan time.Duration, 1)
+		opts  = &Options{
+			ReadTimeout: 100 * time.Millisecond,
+			OnStale:     func() { stale <- time.Since(start) },
+		}
+	)
+	err := NotifyWithOptions(context.Background(), server.URL, true, make(chan *Event), opts)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 2, requests, "a stale connection is reconnected")
+	require.Len(t, stale, 1)
+	assert.Greater(t, <-stale, 250*time.Millisecond, "heartbeats keep the connection alive")
+}
+
+func TestReadTimeoutSlowConsumer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 4; i++ {
+			_, err := w.Write([]byte("data: event\n\n"))
+			assert.NoError(t, err)
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	var (
+		events int
+		stale  = make(chan struct{}, 4)
+		opts   = &Options{
+			ReadTimeout: 100 * time.Millisecond,
+			OnStale:     func() { stale <- struct{}{} },
+		}
+	)
+	// each event takes longer to handle than the timeout
+	err := NotifyTarget(context.Background(), server.URL, false, Target{Func: func(*Event) error {
+		events++
+		time.Sleep(150 * time.Millisecond)
+		return nil
+	}}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, events)
+	assert.Empty(t, stale, "time spent delivering does not count")
+}
+
+func TestIDUpdatePredicate(t *testing.T) {
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		switch len(lastEventIDs) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 10\n\n" +
+				"id: 1\nevent: durable\ndata: a\n\n" +
+				"id: 2\nevent: ephemeral\ndata: b\n\n"))
+			assert.NoError(t, err)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	var (
+		evCh = make(chan *Event, 2)
+		opts = &Options{IDUpdatePredicate: func(ev *Event) bool { return ev.Type != "ephemeral" }}
+	)
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, opts)
+	assert.Error(t, err)
+	close(evCh)
+
+	var ids []string
+	for event := range evCh {
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []string{"1", "2"}, ids)
+	assert.Equal(t, []string{"", "1"}, lastEventIDs)
+}
+
+func TestIDUpdatePredicateInheritedID(t *testing.T) {
+	const stream = "id: 1\nevent: durable\ndata: a\n\n" +
+		"id: 2\nevent: ephemeral\ndata: b\n\n" +
+		"event: durable\ndata: c\n\n\n"
+
+	evCh := make(chan *Event, 3)
+	s := newSession("", Target{Chan: evCh}, &Options{IDUpdatePredicate: func(ev *Event) bool {
+		return ev.Type == "durable"
+	}})
+	require.NoError(t, s.loop(context.Background(), strings.NewReader(stream)))
+	assert.Equal(t, "1", s.id, "the rejected event's ID is not adopted through the next event")
+	<-evCh
+	<-evCh
+	assert.Equal(t, "2", (<-evCh).ID, "the event still carries the inherited ID")
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestOptionsClient(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	defaultClient := Client
+	defer func() { Client = defaultClient }()
+	Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		t.Error("package Client used")
+		return nil, errors.New("wrong client")
+	})}
+
+	var used int
+	opts := &Options{Client: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		used++
+		return http.DefaultTransport.RoundTrip(req)
+	})}}
+	err := NotifyWithOptions(context.Background(), server.URL, true, make(chan *Event, 1), opts)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 2, used, "the client is used for reconnects too")
+	assert.Equal(t, 2, requests)
+}
+
+func TestShouldReconnectTransportError(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 10\ndata: event 1\n\n"))
+			assert.NoError(t, err)
+		default:
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	// fail the second attempt before it reaches the server
+	var attempts int
+	defaultClient := Client
+	defer func() { Client = defaultClient }()
+	Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 2 {
+			return nil, errors.New("dial failure")
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+
+	var transportErrs []error
+	opts := &Options{ShouldReconnect: func(err error) bool {
+		var te *TransportError
+		if errors.As(err, &te) {
+			transportErrs = append(transportErrs, err)
+			return true
+		}
+		return false
+	}}
+
+	evCh := make(chan *Event, 1)
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, opts)
+	require.Error(t, err)
+	assert.True(t, strings.HasSuffix(err.Error(), "500"))
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 2, requests)
+	require.Len(t, transportErrs, 1)
+	assert.Contains(t, transportErrs[0].Error(), "dial failure")
+	assert.Len(t, evCh, 1)
+}
+
+func TestNoReplay(t *testing.T) {
+	var (
+		lastEventIDs []string
+		queries      []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		queries = append(queries, r.URL.RawQuery)
+		if len(lastEventIDs) > 2 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\nid: " + strconv.Itoa(len(lastEventIDs)) + "\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	evCh := make(chan *Event, 2)
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, &Options{NoReplay: true, ResumeQueryParam: "since"})
+	assert.Error(t, err)
+	assert.Len(t, evCh, 2)
+	assert.Equal(t, []string{"", "", ""}, lastEventIDs)
+	assert.Equal(t, []string{"", "", ""}, queries)
+}
+
+func TestOnGap(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 2 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 100\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var gaps []time.Duration
+	evCh := make(chan *Event, 2)
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, &Options{
+		OnGap: func(d time.Duration) { gaps = append(gaps, d) },
+	})
+	assert.Error(t, err)
+	assert.Len(t, evCh, 2)
+	require.Len(t, gaps, 1)
+	assert.InDelta(t, 100*time.Millisecond, gaps[0], float64(50*time.Millisecond))
+}
+
+func TestInjectHeaders(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("traceparent"))
+		if len(got) > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	type traceKey struct{}
+	var (
+		ctx  = context.WithValue(context.Background(), traceKey{}, traceparent)
+		evCh = make(chan *Event, 1)
+		opts = &Options{InjectHeaders: func(ctx context.Context, h http.Header) {
+			h.Set("traceparent", ctx.Value(traceKey{}).(string))
+		}}
+	)
+	err := NotifyWithOptions(ctx, server.URL, true, evCh, opts)
+	assert.Error(t, err)
+	assert.Equal(t, []string{traceparent, traceparent}, got)
+}
+
+func TestHeaderOption(t *testing.T) {
+	type request struct{ auth, accept string }
+	var got []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, request{r.Header.Get("Authorization"), r.Header.Get("Accept")})
+		if len(got) > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	opts := &Options{Header: http.Header{
+		"Authorization": {"Bearer token"},
+		"accept":        {"application/json"},
+	}}
+	err := NotifyWithOptions(context.Background(), server.URL, true, make(chan *Event, 1), opts)
+	assert.Error(t, err)
+	assert.Equal(t, []request{
+		{"Bearer token", "text/event-stream"},
+		{"Bearer token", "text/event-stream"},
+	}, got)
+}
+
+func TestFirstEventTimeout(t *testing.T) {
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+		if len(requests) > 1 {
+			w.WriteHeader(204)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\n\n"))
+		assert.NoError(t, err)
+		for {
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+				_, err = w.Write([]byte(": keepalive\n"))
+				assert.NoError(t, err)
+			}
+		}
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	Logger.SetOutput(&logs)
+	defer Logger.SetOutput(io.Discard)
+
+	evCh := make(chan *Event)
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, &Options{FirstEventTimeout: 100 * time.Millisecond})
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, 204, statusErr.StatusCode)
+	require.Len(t, requests, 2)
+	assert.InDelta(t, 110*time.Millisecond, requests[1].Sub(requests[0]), float64(50*time.Millisecond))
+	assert.Contains(t, logs.String(), ErrFirstEventTimeout.Error())
+}
+
+func TestConnectTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select { // delay the response headers
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		time.Sleep(150 * time.Millisecond) // longer than the timeout, but streaming
+		_, err := w.Write([]byte("data: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	opts := &Options{ConnectTimeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	err := NotifyWithOptions(context.Background(), server.URL+"/slow", true, make(chan *Event), opts)
+	var transportErr *TransportError
+	require.ErrorAs(t, err, &transportErr)
+	assert.ErrorIs(t, err, ErrConnectTimeout)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	evCh := make(chan *Event, 1)
+	require.NoError(t, NotifyWithOptions(context.Background(), server.URL, false, evCh, opts))
+	assert.Len(t, evCh, 1, "the timeout does not apply once the stream is open")
+}
+
+func TestNotifyResumableRetryWithoutReconnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 5000\nid: 7\ndata: event\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	lastID, wait, err := NotifyResumable(context.Background(), server.URL, false, make(chan *Event, 1), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "7", lastID)
+	assert.Equal(t, 5*time.Second, wait)
+}
+
+func TestClientTimeoutWarning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	Logger.SetOutput(&logs)
+	defer Logger.SetOutput(io.Discard)
+
+	require.NoError(t, Notify(context.Background(), server.URL, false, make(chan *Event)))
+	assert.NotContains(t, logs.String(), "Client.Timeout")
+
+	defaultClient := Client
+	defer func() { Client = defaultClient }()
+	Client = &http.Client{Timeout: time.Minute}
+
+	require.NoError(t, Notify(context.Background(), server.URL, false, make(chan *Event)))
+	assert.Contains(t, logs.String(), "warning: Client.Timeout of 1m0s will end the stream")
+}
+
+func TestSequence(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 10\ndata: a\n\ndata: b\n\n"))
+			assert.NoError(t, err)
+		case 2:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("data: c\n\n"))
+			assert.NoError(t, err)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	evCh := make(chan *Event, 3)
+	err := NotifyWithOptions(context.Background(), server.URL, true, evCh, &Options{Sequence: true})
+	assert.Error(t, err)
+	close(evCh)
+
+	var got [][2]uint64
+	for event := range evCh {
+		got = append(got, [2]uint64{event.Seq, event.ConnIndex})
+	}
+	assert.Equal(t, [][2]uint64{{0, 0}, {1, 1}, {2, 0}}, got)
+}
+
+func TestRawTap(t *testing.T) {
+	const stream = ": hello\r\nid: 1\ndata: event 1\n\nfoo: bar\ndata: event 2\n\ndata: trailing"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte(stream))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		raw  bytes.Buffer
+		evCh = make(chan *Event, 2)
+	)
+	require.NoError(t, NotifyWithOptions(context.Background(), server.URL, false, evCh, &Options{RawTap: &raw}))
+	assert.Equal(t, stream, raw.String())
+	assert.Len(t, evCh, 2)
+}
+
+func disconnectingServer(t *testing.T) *httptest.Server {
+	var count int
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch count {
+		case 0:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("data: event 1\nretry: 100\nid: myid\n\n"))
+			assert.NoError(t, err)
+			count++
+		default:
+			require.Equal(t, "myid", r.Header.Get("Last-Event-ID"))
+			w.WriteHeader(204)
+		}
+	}))
+}
+
+func waitingServer(t *testing.T) (*httptest.Server, chan struct{}) {
+	done := make(chan struct{})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // wait for the client to go away
+		done <- struct{}{}
+	})), done
+}
+
+func TestFlushOnEOF(t *testing.T) {
+	for _, stream := range []string{
+		"data: first\n\ndata: last",
+		"data: first\n\ndata: last\n",
+		"data: first\n\ndata: last\r",
+		"data: first\n\ndata: last\n\n",
+	} {
+		events := func(opts *Options) []string {
+			evCh := make(chan *Event, 2)
+			require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), iotest.OneByteReader(strings.NewReader(stream))))
+			close(evCh)
+			var data []string
+			for ev := range evCh {
+				data = append(data, string(ev.Data))
+			}
+			return data
+		}
+		assert.Equal(t, []string{"first", "last"}, events(&Options{FlushOnEOF: true}), "%q", stream)
+		if !strings.HasSuffix(stream, "\n\n") {
+			assert.Equal(t, []string{"first"}, events(nil), "%q", stream)
+		}
+	}
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		line, name, val string
+	}{
+		{"data: http://example.com", "data", "http://example.com"},
+		{"data: a:b: c", "data", "a:b: c"},
+		{"data:  x", "data", " x"},
+		{"data:\tx", "data", "\tx"},
+		{"data: \tx", "data", "\tx"},
+		{"data: ", "data", ""},
+		{"data:  ", "data", " "},
+		{"data:x", "data", "x"},
+		{"data:", "data", ""},
+		{"data", "data", ""},
+		{": comment", "", "comment"},
+	}
+	for _, tt := range tests {
+		name, val := parseField([]byte(tt.line))
+		assert.Equal(t, tt.name, string(name), tt.line)
+		assert.Equal(t, tt.val, string(val), tt.line)
+	}
 }
@@ -0,0 +1,11 @@
+package sse
+
+//ParseStats summarizes what was read on a single connection, see
+//Options.OnStats.
+type ParseStats struct {
+	Events         int // events delivered
+	Comments       int // comment lines
+	UnknownFields  int // fields with a name that is not part of the protocol, nor configured
+	IgnoredRetries int // retry: fields that were not an unsigned integer
+	MalformedLines int // lines without a colon that are not a known field, and an unterminated last line
+}
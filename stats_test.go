@@ -0,0 +1,35 @@
+package sse
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnStats(t *testing.T) {
+	const stream = ": hello\n" +
+		"data: event 1\n\n" +
+		"retry: soon\nfoo: bar\nbaz\ndata\n\n" +
+		": bye\nlen: 7\nretry: 10\ndata: event 3\n\n" +
+		"data: cut short"
+
+	var (
+		stats []ParseStats
+		evCh  = make(chan *Event, 3)
+		opts  = &Options{
+			LengthField: "len",
+			OnStats:     func(st ParseStats) { stats = append(stats, st) },
+		}
+	)
+	require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+	assert.Equal(t, []ParseStats{{
+		Events:         3,
+		Comments:       2,
+		UnknownFields:  1,
+		IgnoredRetries: 1,
+		MalformedLines: 2,
+	}}, stats)
+}
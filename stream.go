@@ -0,0 +1,123 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+//reconnectHistorySize is the number of reconnects kept by a Stream.
+const reconnectHistorySize = 16
+
+//Stream delivers the events of a stream that is received in the background.
+//Unlike the channel passed to Notify, the channel returned by Events is owned
+//by the Stream: it is closed exactly once, after the final event has been
+//delivered and the stream has stopped.
+type Stream struct {
+	events  chan *Event
+	err     error
+	history reconnectHistory
+
+	cancel context.CancelCauseFunc
+	done   chan struct{} // closed once the stream has stopped
+}
+
+//Subscribe starts receiving the stream at uri in a new goroutine, as
+//NotifyWithOptions would, and returns immediately. The stream runs until ctx
+//is done, it stops on its own or Close is called.
+func Subscribe(ctx context.Context, uri string, retry bool, opts *Options) *Stream {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	s := &Stream{events: make(chan *Event), cancel: cancel, done: make(chan struct{})}
+	sess := newSession(uri, Target{Chan: s.events}, opts)
+	sess.history = &s.history
+	go func() {
+		defer close(s.done)
+		_, _, s.err = notify(ctx, retry, sess)
+		cancel(nil)
+		close(s.events)
+	}()
+	return s
+}
+
+//Close stops the stream, without the need to receive its remaining events,
+//and waits for its goroutine to finish, after which the channel returned by
+//Events is closed. It returns the error that stopped the stream if it had
+//already stopped for another reason, and nil otherwise. Calling Close more
+//than once is safe.
+func (s *Stream) Close() error {
+	s.cancel(ErrStreamClosed)
+	<-s.done
+	if errors.Is(s.err, ErrStreamClosed) {
+		return nil
+	}
+	return s.err
+}
+
+//Events returns the channel on which the events of the stream are delivered.
+func (s *Stream) Events() <-chan *Event {
+	return s.events
+}
+
+//Err returns the error that stopped the stream, if any. It is only valid once
+//the channel returned by Events has been closed.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+//ReconnectHistory returns the most recent reconnects of the stream, oldest
+//first.
+func (s *Stream) ReconnectHistory() []Reconnect {
+	return s.history.list()
+}
+
+//Reconnect describes a reconnect of a stream.
+type Reconnect struct {
+	//Time is when the connection was lost or the connection attempt failed.
+	Time time.Time
+	//Reason is one of "stream ended", "connection lost" or
+	//"connection failed".
+	Reason string
+	//Err is the error that caused the reconnect, nil if the stream ended
+	//cleanly.
+	Err error
+	//Wait is the time waited before reconnecting, after Options.Jitter,
+	//BackoffFactor, MinReconnectInterval and Retry-After were applied.
+	Wait time.Duration
+}
+
+//reconnectHistory is a ring buffer of the last reconnectHistorySize reconnects.
+type reconnectHistory struct {
+	mu      sync.Mutex
+	entries [reconnectHistorySize]Reconnect
+	n       int // total number of reconnects added
+}
+
+func (h *reconnectHistory) add(r Reconnect) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries[h.n%reconnectHistorySize] = r
+	h.n++
+}
+
+func (h *reconnectHistory) list() []Reconnect {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []Reconnect
+	for i := max(0, h.n-reconnectHistorySize); i < h.n; i++ {
+		out = append(out, h.entries[i%reconnectHistorySize])
+	}
+	return out
+}
+
+//addReconnect records a reconnect after waiting for wait in the history of s,
+//if it keeps one, and its cause for Metrics.Reconnect.
+func (s *session) addReconnect(reason string, err error, wait time.Duration) {
+	s.cause = err
+	if s.history != nil {
+		s.history.add(Reconnect{Time: time.Now(), Reason: reason, Err: err, Wait: wait})
+	}
+}
@@ -0,0 +1,183 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: event 1\n\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var events []*Event
+	s := Subscribe(context.Background(), server.URL, false, nil)
+	for event := range s.Events() {
+		events = append(events, event)
+	}
+
+	require.NoError(t, s.Err())
+	require.Len(t, events, 2)
+	header := events[0].Header
+	require.Equal(t,
+		[]*Event{
+			{URI: server.URL, Data: []byte("event 1"), Header: header},
+			{URI: server.URL, Data: []byte("event 2"), Header: header},
+		},
+		events,
+	)
+}
+
+func TestSubscribeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer server.Close()
+
+	s := Subscribe(context.Background(), server.URL, true, nil)
+	for range s.Events() {
+		t.Fatal("unexpected event")
+	}
+	assert.Error(t, s.Err())
+}
+
+func TestStreamClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: event 1\n\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	s := Subscribe(context.Background(), server.URL, true, nil)
+	<-s.Events() // the second event is never received
+
+	done := make(chan error)
+	go func() { done <- s.Close() }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close did not stop the stream")
+	}
+	for range s.Events() {
+		t.Fatal("unexpected event")
+	}
+	assert.ErrorIs(t, s.Err(), ErrStreamClosed)
+	assert.NoError(t, s.Close(), "closing again is safe")
+}
+
+func TestStreamCloseAfterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer server.Close()
+
+	s := Subscribe(context.Background(), server.URL, true, nil)
+	for range s.Events() {
+	}
+	var statusErr *StatusError
+	assert.ErrorAs(t, s.Close(), &statusErr)
+}
+
+func TestReconnectHistory(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1, 3:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+			assert.NoError(t, err)
+		case 2:
+			w.WriteHeader(503)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	s := Subscribe(context.Background(), server.URL, true, &Options{
+		ShouldReconnect: func(err error) bool {
+			var statusErr *StatusError
+			return errors.As(err, &statusErr) && statusErr.StatusCode == 503
+		},
+	})
+	for range s.Events() {
+	}
+	var statusErr *StatusError
+	require.ErrorAs(t, s.Err(), &statusErr)
+	assert.Equal(t, 204, statusErr.StatusCode)
+
+	history := s.ReconnectHistory()
+	require.Len(t, history, 3)
+	assert.Equal(t, "stream ended", history[0].Reason)
+	assert.NoError(t, history[0].Err)
+	assert.Equal(t, "connection failed", history[1].Reason)
+	assert.True(t, strings.HasSuffix(history[1].Err.Error(), "503"))
+	assert.Equal(t, "stream ended", history[2].Reason)
+	for i, r := range history {
+		assert.Equal(t, 10*time.Millisecond, r.Wait)
+		if i > 0 {
+			assert.True(t, r.Time.After(history[i-1].Time))
+		}
+	}
+}
+
+func TestReconnectHistoryWait(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, err := w.Write([]byte("retry: 10\ndata: event\n\n"))
+			assert.NoError(t, err)
+		case 2, 3:
+			w.WriteHeader(503)
+		default:
+			w.WriteHeader(204)
+		}
+	}))
+	defer server.Close()
+
+	s := Subscribe(context.Background(), server.URL, true, &Options{
+		RetryOnStatus:        func(code int) bool { return code == 503 },
+		MinReconnectInterval: 15 * time.Millisecond,
+		BackoffFactor:        2,
+	})
+	for range s.Events() {
+	}
+
+	var waits []time.Duration
+	for _, r := range s.ReconnectHistory() {
+		waits = append(waits, r.Wait)
+	}
+	// the 10ms reconnection time is raised to the minimum, then doubled
+	assert.Equal(t, []time.Duration{15 * time.Millisecond, 15 * time.Millisecond, 20 * time.Millisecond}, waits)
+}
+
+func TestReconnectHistoryRing(t *testing.T) {
+	var h reconnectHistory
+	for i := 0; i < reconnectHistorySize+3; i++ {
+		h.add(Reconnect{Wait: time.Duration(i)})
+	}
+
+	list := h.list()
+	require.Len(t, list, reconnectHistorySize)
+	assert.Equal(t, time.Duration(3), list[0].Wait)
+	assert.Equal(t, time.Duration(reconnectHistorySize+2), list[reconnectHistorySize-1].Wait)
+}
@@ -0,0 +1,63 @@
+package sse
+
+import "context"
+
+//Target is where the events of a stream are delivered. Exactly one of Chan and
+//Func must be set.
+type Target struct {
+	//Chan receives each event, as with Notify.
+	Chan chan<- *Event
+
+	//Func is called synchronously for each event. If it returns an error, the
+	//stream stops and that error is returned, even if retry is enabled.
+	//
+	//The stream is not read while Func runs, so returning acknowledges the
+	//event. A slow Func therefore applies backpressure all the way to the
+	//server: once the small read buffer and the connection's TCP buffers are
+	//full, the server's writes block. Servers may drop such a client, and
+	//FirstEventTimeout keeps running during the first call.
+	Func func(ev *Event) error
+}
+
+//validate returns ErrNilChan if t has no destination set, and
+//ErrTargetConflict if it has both.
+func (t Target) validate() error {
+	switch {
+	case t.Chan == nil && t.Func == nil:
+		return ErrNilChan
+	case t.Chan != nil && t.Func != nil:
+		return ErrTargetConflict
+	}
+	return nil
+}
+
+//deliver hands ev to the target, giving up with the cause of ctx if it is
+//done before a channel receiver is ready.
+func (t Target) deliver(ctx context.Context, ev *Event) error {
+	if t.Func != nil {
+		return t.Func(ev)
+	}
+	select {
+	case t.Chan <- ev:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+//NotifyTarget is like NotifyWithOptions, but delivers events to whichever of
+//the channel or callback of t is set.
+func NotifyTarget(ctx context.Context, uri string, retry bool, t Target, opts *Options) error {
+	_, _, err := notify(ctx, retry, newSession(uri, t, opts))
+	return err
+}
+
+//NotifyFunc is like Notify, but calls fn synchronously for each event instead
+//of sending it down a channel. If fn returns an error, the stream stops and
+//that error is returned, even if retry is enabled.
+func NotifyFunc(ctx context.Context, uri string, retry bool, fn func(*Event) error) error {
+	if fn == nil {
+		return ErrNilChan
+	}
+	return NotifyTarget(ctx, uri, retry, Target{Func: fn}, nil)
+}
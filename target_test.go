@@ -0,0 +1,137 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func twoEventServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\ndata: event 1\n\ndata: event 2\n\n"))
+		assert.NoError(t, err)
+	}))
+}
+
+func TestNotifyTarget(t *testing.T) {
+	server := twoEventServer(t)
+	defer server.Close()
+
+	t.Run("channel", func(t *testing.T) {
+		evCh := make(chan *Event, 2)
+		require.NoError(t, NotifyTarget(context.Background(), server.URL, false, Target{Chan: evCh}, nil))
+		assert.Len(t, evCh, 2)
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		var data []string
+		fn := func(ev *Event) error {
+			data = append(data, string(ev.Data))
+			return nil
+		}
+		require.NoError(t, NotifyTarget(context.Background(), server.URL, false, Target{Func: fn}, nil))
+		assert.Equal(t, []string{"event 1", "event 2"}, data)
+	})
+
+	t.Run("callback error stops retrying stream", func(t *testing.T) {
+		var (
+			calls int
+			stop  = errors.New("stop")
+		)
+		fn := func(ev *Event) error {
+			calls++
+			return stop
+		}
+		err := NotifyTarget(context.Background(), server.URL, true, Target{Func: fn}, nil)
+		assert.Equal(t, stop, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("neither", func(t *testing.T) {
+		err := NotifyTarget(context.Background(), server.URL, false, Target{}, nil)
+		assert.Equal(t, ErrNilChan, err)
+	})
+
+	t.Run("both", func(t *testing.T) {
+		fn := func(ev *Event) error { return nil }
+		err := NotifyTarget(context.Background(), server.URL, false, Target{Chan: make(chan *Event), Func: fn}, nil)
+		assert.Equal(t, ErrTargetConflict, err)
+	})
+}
+
+func TestNotifyFunc(t *testing.T) {
+	server := twoEventServer(t)
+	defer server.Close()
+
+	var data []string
+	err := NotifyFunc(context.Background(), server.URL, false, func(ev *Event) error {
+		data = append(data, string(ev.Data))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"event 1", "event 2"}, data)
+
+	stop := errors.New("stop")
+	err = NotifyFunc(context.Background(), server.URL, true, func(ev *Event) error { return stop })
+	assert.Equal(t, stop, err)
+
+	assert.Equal(t, ErrNilChan, NotifyFunc(context.Background(), server.URL, false, nil))
+}
+
+func TestTargetFuncBackpressure(t *testing.T) {
+	const (
+		events = 256 << 10
+		event  = "data: " + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef" + "\n\n"
+	)
+	var written atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < events; i++ {
+			n, err := w.Write([]byte(event))
+			written.Add(int64(n))
+			if err != nil {
+				return
+			}
+			if i%64 == 0 {
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	var (
+		calls   int
+		blocked = make(chan struct{})
+		release = make(chan struct{})
+	)
+	fn := func(ev *Event) error {
+		calls++
+		if calls == 1 {
+			close(blocked)
+			<-release
+		}
+		return nil
+	}
+	done := make(chan error)
+	go func() { done <- NotifyTarget(context.Background(), server.URL, false, Target{Func: fn}, nil) }()
+
+	<-blocked
+	// give the server time to fill the buffers until its writes block
+	time.Sleep(200 * time.Millisecond)
+	stalled := written.Load()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, stalled, written.Load(), "server kept writing while the callback blocked")
+	assert.Less(t, stalled, int64(events*len(event)))
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, events, calls)
+}
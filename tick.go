@@ -0,0 +1,47 @@
+package sse
+
+import (
+	"context"
+	"time"
+)
+
+//TickEventType is the type of the synthetic events sent every
+//Options.TickInterval. A server can send events of this type too, e.g. with
+//"event::tick"; check Event.Synthetic to tell ticks apart.
+const TickEventType = ":tick"
+
+//startTicks sends a tick event down the session's channel every interval
+//until ctx is done or the returned function is called. stop waits for the
+//ticker to exit, so that no tick is sent once it has returned.
+func (s *session) startTicks(ctx context.Context, interval time.Duration) (stop func()) {
+	var (
+		done   = make(chan struct{})
+		exited = make(chan struct{})
+		uri    = s.uri
+	)
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-t.C:
+			}
+			select {
+			case s.t.Chan <- &Event{URI: uri, Type: TickEventType, Synthetic: true}:
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
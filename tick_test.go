@@ -0,0 +1,82 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTickInterval(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: event 1\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		time.Sleep(350 * time.Millisecond)
+		_, err = w.Write([]byte("event::tick\ndata: forged\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	var (
+		evCh = make(chan *Event)
+		done = make(chan error)
+		opts = &Options{TickInterval: 100 * time.Millisecond}
+	)
+	go func() { done <- NotifyWithOptions(context.Background(), server.URL, false, evCh, opts) }()
+
+	var (
+		types     []string
+		synthetic []bool
+		ticks     []time.Time
+	)
+	for running := true; running; {
+		select {
+		case ev := <-evCh:
+			types = append(types, ev.Type)
+			synthetic = append(synthetic, ev.Synthetic)
+			if ev.Synthetic {
+				ticks = append(ticks, time.Now())
+			}
+		case err := <-done:
+			require.NoError(t, err)
+			running = false
+		}
+	}
+	require.Equal(t, []string{"", TickEventType, TickEventType, TickEventType, TickEventType}, types)
+	assert.Equal(t, []bool{false, true, true, true, false}, synthetic, "the server's own :tick event is not synthetic")
+	for i := 1; i < len(ticks); i++ {
+		assert.InDelta(t, 100*time.Millisecond, ticks[i].Sub(ticks[i-1]), float64(50*time.Millisecond))
+	}
+
+	select {
+	case ev := <-evCh:
+		assert.Fail(t, "tick after the stream stopped", ev.Type)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestTickIntervalSubscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("data: event\n\n"))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	// Stopping the stream while a tick is pending used to race with closing
+	// the Stream's channel. Run with -race.
+	for i := 0; i < 20; i++ {
+		s := Subscribe(context.Background(), server.URL, false, &Options{TickInterval: time.Millisecond})
+		for range s.Events() {
+		}
+		require.NoError(t, s.Err())
+	}
+}
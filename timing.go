@@ -0,0 +1,70 @@
+package sse
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ServerTiming is a metric from a Server-Timing response header, see
+//https://www.w3.org/TR/server-timing/.
+type ServerTiming struct {
+	Name        string
+	Duration    time.Duration
+	Description string
+}
+
+//ParseServerTiming returns the metrics listed in the Server-Timing headers of
+//h, e.g. from the response passed to Options.OnConnect. Malformed parameters
+//are ignored.
+func ParseServerTiming(h http.Header) []ServerTiming {
+	var timings []ServerTiming
+	for _, v := range h.Values("Server-Timing") {
+		for _, metric := range splitQuoted(v, ',') {
+			params := splitQuoted(metric, ';')
+			t := ServerTiming{Name: strings.TrimSpace(params[0])}
+			if t.Name == "" {
+				continue
+			}
+			for _, p := range params[1:] {
+				k, v, _ := strings.Cut(p, "=")
+				v = strings.TrimSpace(v)
+				if uq, err := strconv.Unquote(v); err == nil {
+					v = uq
+				}
+				switch strings.ToLower(strings.TrimSpace(k)) {
+				case "dur":
+					if ms, err := strconv.ParseFloat(v, 64); err == nil {
+						t.Duration = time.Duration(ms * float64(time.Millisecond))
+					}
+				case "desc":
+					t.Description = v
+				}
+			}
+			timings = append(timings, t)
+		}
+	}
+	return timings
+}
+
+//splitQuoted splits s on sep, except where sep is inside a quoted string.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++ // skip escaped character
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
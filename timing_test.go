@@ -0,0 +1,40 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	h := http.Header{}
+	h.Add("Server-Timing", `db;dur=53.2, cache;desc="Cache; hit, warm"`)
+	h.Add("Server-Timing", `total;dur=120;desc=app, missing;dur=x`)
+
+	assert.Equal(t, []ServerTiming{
+		{Name: "db", Duration: 53200 * time.Microsecond},
+		{Name: "cache", Description: "Cache; hit, warm"},
+		{Name: "total", Duration: 120 * time.Millisecond, Description: "app"},
+		{Name: "missing"},
+	}, ParseServerTiming(h))
+}
+
+func TestOnConnectServerTiming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Server-Timing", "db;dur=53.2")
+	}))
+	defer server.Close()
+
+	var timings []ServerTiming
+	opts := &Options{OnConnect: func(res *http.Response) {
+		timings = ParseServerTiming(res.Header)
+	}}
+	require.NoError(t, NotifyWithOptions(context.Background(), server.URL, false, make(chan *Event), opts))
+	assert.Equal(t, []ServerTiming{{Name: "db", Duration: 53200 * time.Microsecond}}, timings)
+}
@@ -0,0 +1,34 @@
+package sse
+
+import (
+	"context"
+	"errors"
+)
+
+//NotifyTyped is like Notify, but sends the value decode returns for each event
+//down ch, e.g. the result of unmarshaling its Data as JSON. If decode returns
+//an error wrapping ErrSkipEvent the event is skipped; any other error stops
+//the stream and is returned, even if retry is enabled.
+func NotifyTyped[T any](ctx context.Context, uri string, retry bool, ch chan<- T, decode func(*Event) (T, error)) error {
+	if ch == nil {
+		return ErrNilChan
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return NotifyTarget(ctx, uri, retry, Target{Func: func(ev *Event) error {
+		v, err := decode(ev)
+		if errors.Is(err, ErrSkipEvent) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case ch <- v:
+			return nil
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}}, nil)
+}
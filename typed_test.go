@@ -0,0 +1,68 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type price struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price"`
+}
+
+func decodePrice(ev *Event) (price, error) {
+	var p price
+	if ev.Type == "heartbeat" {
+		return p, ErrSkipEvent
+	}
+	if err := json.Unmarshal(ev.Data, &p); err != nil {
+		return p, fmt.Errorf("decoding event %q: %w", ev.ID, err)
+	}
+	return p, nil
+}
+
+func TestNotifyTyped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte(`data: {"symbol": "ABC", "price": 1.5}
+
+event: heartbeat
+data: {}
+
+data: {"symbol": "XYZ", "price": 20}
+
+`))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	ch := make(chan price, 2)
+	require.NoError(t, NotifyTyped(context.Background(), server.URL, false, ch, decodePrice))
+	close(ch)
+
+	var prices []price
+	for p := range ch {
+		prices = append(prices, p)
+	}
+	assert.Equal(t, []price{{"ABC", 1.5}, {"XYZ", 20}}, prices)
+}
+
+func TestNotifyTypedDecodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, err := w.Write([]byte("retry: 10\nid: 1\ndata: not json\n\n"))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	err := NotifyTyped(context.Background(), server.URL, true, make(chan price, 1), decodePrice)
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+}
@@ -0,0 +1,23 @@
+package sse
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+//String returns the event's Data as a string, with invalid UTF-8 sequences
+//replaced by U+FFFD.
+func (e *Event) String() string {
+	return strings.ToValidUTF8(string(e.Data), string(utf8.RuneError))
+}
+
+//validateUTF8 replaces invalid UTF-8 sequences in the data of ev, see
+//Options.ValidateUTF8.
+func (s *session) validateUTF8(ev *Event) {
+	if utf8.Valid(ev.Data) {
+		return
+	}
+	s.logf(LogError, "event %q has invalid UTF-8 data, replacing invalid sequences", ev.ID)
+	ev.Data = bytes.ToValidUTF8(ev.Data, []byte(string(utf8.RuneError)))
+}
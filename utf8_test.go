@@ -0,0 +1,47 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventString(t *testing.T) {
+	for data, want := range map[string]string{
+		"plain":            "plain",
+		"héllo":            "héllo",
+		"bad \xff byte":    "bad � byte",
+		"cut \xe2\x82":     "cut �",
+		"\xc0\xafoverlong": "�overlong",
+		"":                 "",
+	} {
+		assert.Equal(t, want, (&Event{Data: []byte(data)}).String(), "%q", data)
+	}
+}
+
+func TestValidateUTF8(t *testing.T) {
+	const stream = "id: 1\ndata: ok \xe2\x82\xac\n\nid: 2\ndata: bad \xff\xfe\n\n"
+
+	var buf bytes.Buffer
+	defaultLogger := Logger
+	defer func() { Logger = defaultLogger }()
+	Logger = log.New(&buf, "", 0)
+
+	evCh := make(chan *Event, 2)
+	opts := &Options{ValidateUTF8: true}
+	require.NoError(t, newSession("", Target{Chan: evCh}, opts).loop(context.Background(), strings.NewReader(stream)))
+	assert.Equal(t, "ok €", string((<-evCh).Data))
+	assert.Equal(t, "bad �", string((<-evCh).Data))
+	assert.Contains(t, buf.String(), `event "2" has invalid UTF-8 data`)
+	assert.NotContains(t, buf.String(), `event "1"`)
+
+	evCh = make(chan *Event, 2)
+	require.NoError(t, newSession("", Target{Chan: evCh}, nil).loop(context.Background(), strings.NewReader(stream)))
+	<-evCh
+	assert.Equal(t, []byte("bad \xff\xfe"), (<-evCh).Data, "data is passed through by default")
+}
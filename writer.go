@@ -0,0 +1,90 @@
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+//Writer sends events to the client of an http.Handler, flushing each one so
+//that it is delivered immediately.
+type Writer struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+//NewWriter prepares w for sending an event stream and flushes its headers. It
+//finds a flusher through middleware wrappers that implement
+//Unwrap() http.ResponseWriter, as http.ResponseController does, and returns
+//ErrNoFlusher if there is none.
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil, ErrNoFlusher
+		}
+		return nil, err
+	}
+	return &Writer{w: w, rc: rc}, nil
+}
+
+//Send writes ev to the client and flushes it.
+func (w *Writer) Send(ev *Event) error {
+	if err := writeEvent(w.w, ev, false); err != nil {
+		return err
+	}
+	return w.rc.Flush()
+}
+
+//writeEvent serializes ev as an event stream block: its ID and Type, if
+//non-empty, then one data: field per line of Data and a terminating blank
+//line. Without Type and Data the block only sets the last event ID, unless
+//dispatch is true, which adds an empty data: field so that it is an event.
+func writeEvent(w io.Writer, ev *Event, dispatch bool) error {
+	if strings.ContainsAny(ev.ID, "\r\n") || strings.ContainsAny(ev.Type, "\r\n") {
+		return fmt.Errorf("event id and type must not contain line breaks")
+	}
+
+	var buf bytes.Buffer
+	if ev.ID != "" {
+		buf.WriteString(iName + ": " + ev.ID + "\n")
+	}
+	if ev.Type != "" {
+		buf.WriteString(eName + ": " + ev.Type + "\n")
+	}
+	if len(ev.Data) != 0 || dispatch && ev.Type == "" {
+		for _, line := range splitLines(ev.Data) {
+			buf.WriteString(dName + ": ")
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+//splitLines splits data on each CRLF, LF or CR, which all end a line in an
+//event stream.
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	for {
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			return append(lines, data)
+		}
+		lines = append(lines, data[:i])
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+		data = data[i+1:]
+	}
+}
@@ -0,0 +1,73 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//bufferingWriter is a middleware wrapper hiding the Flusher of the writer it
+//wraps.
+type bufferingWriter struct {
+	http.ResponseWriter
+}
+
+//unwrappingWriter hides the Flusher like bufferingWriter, but allows
+//http.ResponseController to find it.
+type unwrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestNewWriterNoFlusher(t *testing.T) {
+	_, err := NewWriter(bufferingWriter{httptest.NewRecorder()})
+	assert.Equal(t, ErrNoFlusher, err)
+}
+
+func TestNewWriterUnwrap(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w, err := NewWriter(unwrappingWriter{rec})
+	require.NoError(t, err)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+
+	rec.Flushed = false
+	require.NoError(t, w.Send(&Event{ID: "1", Type: "greeting", Data: []byte("hello\nworld")}))
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "id: 1\nevent: greeting\ndata: hello\ndata: world\n\n", rec.Body.String())
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	events := []*Event{
+		{ID: "1", Data: []byte("a")},
+		{ID: "1", Type: "t", Data: []byte("b\r\nc\rd")},
+	}
+
+	rec := httptest.NewRecorder()
+	w, err := NewWriter(rec)
+	require.NoError(t, err)
+	for _, ev := range events {
+		require.NoError(t, w.Send(ev))
+	}
+	assert.Error(t, w.Send(&Event{ID: "bad\nid"}))
+
+	evCh := make(chan *Event, len(events))
+	require.NoError(t, newSession("", Target{Chan: evCh}, nil).loop(context.Background(), strings.NewReader(rec.Body.String())))
+	close(evCh)
+	var got []*Event
+	for ev := range evCh {
+		got = append(got, ev)
+	}
+	assert.Equal(t, []*Event{
+		{ID: "1", Data: []byte("a")},
+		{ID: "1", Type: "t", Data: []byte("b\nc\nd")},
+	}, got)
+}